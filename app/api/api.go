@@ -4,7 +4,10 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/M-Fisher/companies_api/app/api/base"
+	aa "github.com/M-Fisher/companies_api/app/api/endpoints/accounts"
 	ca "github.com/M-Fisher/companies_api/app/api/endpoints/companies"
+	ta "github.com/M-Fisher/companies_api/app/api/endpoints/tokens"
+	ua "github.com/M-Fisher/companies_api/app/api/endpoints/usage"
 	"github.com/M-Fisher/companies_api/app/internal/server"
 )
 
@@ -16,6 +19,31 @@ func SetAPIRouter(s *server.Server, p string) {
 			Srv: s,
 		},
 	})
+	setSubRouterByStruct(apiRouter, "/service-accounts", &aa.AccountsAPI{
+		API: base.API{
+			Srv: s,
+		},
+	})
+	setSubRouterByStruct(apiRouter, "/api-keys", &aa.APIKeysAPI{
+		API: base.API{
+			Srv: s,
+		},
+	})
+	setSubRouterByStruct(apiRouter, "/tokens", &ta.TokensAPI{
+		API: base.API{
+			Srv: s,
+		},
+	})
+	setSubRouterByStruct(apiRouter, "/usage", &ua.UsageAPI{
+		API: base.API{
+			Srv: s,
+		},
+	})
+	setSubRouterByStruct(r, "/auth", &aa.TokenAPI{
+		API: base.API{
+			Srv: s,
+		},
+	})
 }
 
 func setSubRouterByStruct(r *mux.Router, p string, a base.APIInterface) {
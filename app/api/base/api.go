@@ -7,15 +7,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 
+	"github.com/M-Fisher/companies_api/app/internal/clientip"
 	"github.com/M-Fisher/companies_api/app/internal/logger"
 	"github.com/M-Fisher/companies_api/app/internal/server"
+	"github.com/M-Fisher/companies_api/app/internal/services/accounts"
 	"github.com/M-Fisher/companies_api/app/internal/services/auth"
 )
 
@@ -26,35 +31,251 @@ type APIInterface interface {
 
 type Response map[string]interface{}
 
+// JSONHandler handler of API endpoint, result is sent to client as JSON
+type JSONHandler func(ctx context.Context, rw http.ResponseWriter, r *http.Request) (interface{}, error)
+
+// Route endpoint of API and scopes the caller must be granted to use it
+type Route struct {
+	Path    string
+	Method  string
+	Handler JSONHandler
+	// Scopes required scopes, the route does not require authorization if empty
+	Scopes []string
+	// Idempotent POST route executes requests with Idempotency-Key once, the response is stored and replayed
+	// to retries, so routes whose responses carry secrets must not set it
+	Idempotent bool
+}
+
 // API all other API should extend this struct
 type API struct {
 	Srv    *server.Server
 	Router *mux.Router
 }
 
-func (a *API) VerifyUser(r *http.Request) (uint64, error) {
+// APIKeyHeader header of API key, it is accepted instead of Bearer token
+const APIKeyHeader = "X-API-Key"
+
+type userContextType int
+
+const userKey userContextType = iota
+
+// defaultClientIP resolver used when server has none, forwarding headers are not trusted
+var defaultClientIP = &clientip.Resolver{}
+
+// ClientIP address of the client of the request, forwarding headers are honored only from trusted proxies
+func (a *API) ClientIP(r *http.Request) string {
+	if a.Srv.ClientIP != nil {
+		return a.Srv.ClientIP.ClientIP(r)
+	}
+	return defaultClientIP.ClientIP(r)
+}
+
+// VerifyUser authenticates the request by API key, Bearer token or client certificate, the user verified by middleware is reused
+func (a *API) VerifyUser(r *http.Request) (*auth.JWTUser, error) {
+	if user, ok := r.Context().Value(userKey).(*auth.JWTUser); ok {
+		return user, nil
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return a.verifyAPIKey(r.Context(), key)
+	}
 	reqToken := r.Header.Get("Authorization")
 	splitToken := strings.Split(reqToken, "Bearer")
 	if len(splitToken) > 1 {
-		user, err := new(auth.JWTUser).Parse(strings.TrimSpace(splitToken[1]), a.Srv.Config.JWTSecret)
-		if err == nil {
-			return uint64(user.ID), nil
+		user, err := a.ParseToken(r.Context(), strings.TrimSpace(splitToken[1]))
+		if err != nil {
+			return nil, err
+		}
+		if a.Srv.Revocations != nil && a.Srv.Revocations.IsRevoked(user) {
+			return nil, &TokenError{Reason: `token is revoked`}
+		}
+		return user, nil
+	}
+	if user, ok := a.certificateUser(r); ok {
+		return user, nil
+	}
+	return nil, ErrUnauthorized
+}
+
+// certificateUser user of verified client certificate, certificates without principal are ignored
+func (a *API) certificateUser(r *http.Request) (*auth.JWTUser, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(a.Srv.CertPrincipals) == 0 {
+		return nil, false
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	user, err := a.Srv.CertPrincipals.User(cert)
+	if err != nil {
+		a.Srv.Log.Info("Client certificate is not mapped to user",
+			zap.String("subject", cert.Subject.String()),
+			zap.Error(err),
+		)
+		return nil, false
+	}
+	return user, true
+}
+
+// ParseToken verifies signature and claims of the token, revocation is not checked
+func (a *API) ParseToken(ctx context.Context, token string) (*auth.JWTUser, error) {
+	user, err := new(auth.JWTUser).ParseWithKeys(ctx, token, a.jwtKeys(), a.claimsValidation())
+	if err != nil {
+		return nil, newTokenError(err)
+	}
+	return user, nil
+}
+
+func (a *API) verifyAPIKey(ctx context.Context, key string) (*auth.JWTUser, error) {
+	if a.Srv.AccountsService == nil {
+		return nil, ErrUnauthorized
+	}
+	user, err := a.Srv.AccountsService.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, accounts.ErrInvalidAPIKey) {
+			return nil, &TokenError{Reason: err.Error()}
+		}
+		return nil, fmt.Errorf("verify api key: %w", err)
+	}
+	return user, nil
+}
+
+// SetJSONRoutes registers routes, requests to the routes with scopes are authorized before the handler is called.
+// Requests are rate limited after authorization, so limits apply to the caller. Requests with
+// Idempotency-Key to idempotent routes are executed once, retries get the stored response.
+func (a *API) SetJSONRoutes(routes []Route) {
+	for _, route := range routes {
+		handler := route.Handler
+		if route.Idempotent && route.Method == http.MethodPost {
+			handler = a.idempotencyMiddleware(handler)
+		}
+		handler = a.rateLimitMiddleware(handler)
+		if len(route.Scopes) > 0 {
+			handler = a.scopesMiddleware(route.Scopes, handler)
+		}
+		a.SetJSONHandler(route.Path, handler).Methods(route.Method)
+	}
+}
+
+func (a *API) scopesMiddleware(scopes []string, next JSONHandler) JSONHandler {
+	return func(ctx context.Context, rw http.ResponseWriter, r *http.Request) (interface{}, error) {
+		user, err := a.VerifyUser(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.Srv.AuthService.Authorize(user, scopes...); err != nil {
+			logger.FromContext(ctx).Info("Insufficient permissions",
+				zap.Int64("user_id", user.ID),
+				zap.Strings("required_scopes", scopes),
+				zap.Error(err),
+			)
+			return nil, ErrForbidden
+		}
+		return next(ctx, rw, r.WithContext(context.WithValue(r.Context(), userKey, user)))
+	}
+}
+
+// rateLimitMiddleware limits requests of the caller to the route, RateLimit-* headers report the limit.
+// Callers of routes without scopes are verified here, so they are limited as themselves rather than by IP,
+// requests which fail verification are limited by IP and left to the handler to reject.
+func (a *API) rateLimitMiddleware(next JSONHandler) JSONHandler {
+	return func(ctx context.Context, rw http.ResponseWriter, r *http.Request) (interface{}, error) {
+		if _, ok := r.Context().Value(userKey).(*auth.JWTUser); !ok {
+			if user, err := a.VerifyUser(r); err == nil {
+				r = r.WithContext(context.WithValue(r.Context(), userKey, user))
+			}
+		}
+		if err := a.CheckRateLimit(ctx, rw, r, a.principal(r)); err != nil {
+			return nil, err
 		}
-		return 0, ErrUnauthorized
+		return next(ctx, rw, r)
 	}
-	return 0, ErrUnauthorized
 }
 
-func (a *API) SetJSONHandler(path string, f func(ctx context.Context, rw http.ResponseWriter, r *http.Request) (interface{}, error)) *mux.Route {
+// CheckRateLimit takes a token of the principal from the bucket of the route and sets RateLimit-* headers,
+// ErrTooManyRequests is returned when the limit is exceeded. Routes registered without SetJSONRoutes use it
+// to be limited too.
+func (a *API) CheckRateLimit(ctx context.Context, rw http.ResponseWriter, r *http.Request, principal string) error {
+	if a.Srv.RateLimiter == nil {
+		return nil
+	}
+	res, ok, err := a.Srv.RateLimiter.Allow(ctx, r.Method+" "+routeTemplate(r), principal)
+	if err != nil {
+		// unavailable store doesn't make the API unavailable
+		logger.FromContext(ctx).Error("Failed to check rate limit", zap.Error(err))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	h := rw.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		return ErrTooManyRequests
+	}
+	return nil
+}
+
+// principal the caller is limited as: API key, service account, user of the tenant or client IP
+// if the request is not authenticated
+func (a *API) principal(r *http.Request) string {
+	user, ok := r.Context().Value(userKey).(*auth.JWTUser)
+	switch {
+	case !ok:
+		return "ip:" + a.ClientIP(r)
+	case user.APIKeyID != 0:
+		return "api_key:" + strconv.FormatInt(user.APIKeyID, 10)
+	case user.ClientID != "":
+		return "client:" + user.ClientID
+	}
+	// user ids are unique only within the tenant
+	return "user:" + user.TenantID + ":" + strconv.FormatInt(user.ID, 10)
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func (a *API) jwtKeys() auth.KeyProvider {
+	if a.Srv.JWTKeys != nil {
+		return a.Srv.JWTKeys
+	}
+	return &auth.JWTKeys{Secret: a.Srv.Config.JWTSecret}
+}
+
+func (a *API) claimsValidation() auth.ClaimsValidation {
+	return auth.ClaimsValidation{
+		Issuer:        a.Srv.Config.JWTIssuer,
+		Audience:      a.Srv.Config.JWTAudience,
+		MaxAge:        a.Srv.Config.JWTMaxAge,
+		Leeway:        a.Srv.Config.JWTLeeway,
+		RequireExpiry: a.Srv.Config.JWTRequireExpiry,
+		LegacyScopes:  a.Srv.Config.JWTLegacyScopes,
+	}
+}
+
+func (a *API) SetJSONHandler(path string, f JSONHandler) *mux.Route {
 	if a.Router != nil {
 		return a.Router.HandleFunc(path,
 			a.loggingMiddleware(
 				a.panicHandlerMiddleware(
 					func(ctx context.Context, rw http.ResponseWriter, rq *http.Request) {
+						// handler context is cancelled on return, so the work left after client has gone
+						// (e.g. DB queries) is stopped instead of running in background
+						ctx, cancel := context.WithCancel(ctx)
+						defer cancel()
 						var (
 							res  any
 							err  error
-							done = make(chan struct{})
+							done = make(chan struct{}, 1)
 						)
 						go func() {
 							res, err = f(ctx, rw, rq)
@@ -63,7 +284,10 @@ func (a *API) SetJSONHandler(path string, f func(ctx context.Context, rw http.Re
 						for {
 							select {
 							case <-rq.Context().Done():
-								// Received Done signal from parent
+								// Received Done signal from parent. The handler may still be setting
+								// headers (rate limit, idempotency), so it is stopped before the response is written
+								cancel()
+								<-done
 								a.sendErrorResponse(rw, errors.New("request cancelled"))
 								return
 							case <-done:
@@ -125,13 +349,9 @@ func (a *API) loggingMiddleware(
 		for k, v := range rq.Header {
 			headers.WriteString(fmt.Sprintf("%s:%s,", k, v))
 		}
-		remoteAddr := rq.RemoteAddr
-		if chunks := strings.Split(remoteAddr, ":"); len(chunks) > 0 {
-			remoteAddr = chunks[0]
-		}
 		log.Debug("Incoming Request",
 			zap.String("headers", headers.String()),
-			zap.String("remote_addr", remoteAddr),
+			zap.String("remote_addr", a.ClientIP(rq)),
 		)
 
 		ctx := logger.ToContext(rq.Context(), log)
@@ -155,9 +375,27 @@ func (a *API) sendErrorResponse(w http.ResponseWriter, err error) {
 		"status_code": ServerErrorCode,
 		"status_text": err.Error(),
 	}
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		response["payload"] = partial.Payload
+	}
 	errorCode := http.StatusInternalServerError
-	if errors.Is(err, ErrUnauthorized) {
+	switch {
+	case errors.Is(err, ErrUnauthorized):
 		errorCode = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", wwwAuthenticate(err))
+	case errors.Is(err, ErrForbidden):
+		errorCode = http.StatusForbidden
+	case errors.Is(err, ErrNotFound):
+		errorCode = http.StatusNotFound
+	case errors.Is(err, ErrTooManyRequests):
+		errorCode = http.StatusTooManyRequests
+	case errors.Is(err, ErrBadRequest):
+		errorCode = http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		errorCode = http.StatusConflict
+	case errors.Is(err, ErrUnprocessableEntity):
+		errorCode = http.StatusUnprocessableEntity
 	}
 	a.sendResponse(w, response, errorCode)
 }
@@ -1,7 +1,80 @@
 package base
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
 
 const ServerErrorCode = 500
 
-var ErrUnauthorized = errors.New(`not authorized`)
+var (
+	ErrUnauthorized = errors.New(`not authorized`)
+	ErrForbidden    = errors.New(`forbidden`)
+	ErrNotFound     = errors.New(`not found`)
+	// ErrTooManyRequests the caller exceeded rate limit of the route
+	ErrTooManyRequests = errors.New(`too many requests`)
+	ErrBadRequest      = errors.New(`bad request`)
+	// ErrConflict the request conflicts with a request in progress or with stored data
+	ErrConflict = errors.New(`conflict`)
+	// ErrUnprocessableEntity the request is well-formed but can't be processed, e.g. reused idempotency key
+	ErrUnprocessableEntity = errors.New(`unprocessable entity`)
+)
+
+// PartialError error of the request which was partly done before it failed,
+// the payload describes the done part and is sent along with the error
+type PartialError struct {
+	Err     error
+	Payload Response
+}
+
+func (e *PartialError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// tokenErrorReasons token validation errors which are reported to the client,
+// other errors (e.g. invalid signature) are reported as invalid token
+var tokenErrorReasons = []error{
+	auth.ErrTokenExpired,
+	auth.ErrTokenNotValidYet,
+	auth.ErrTokenTooOld,
+	auth.ErrTokenExpiryMissing,
+	auth.ErrInvalidIssuer,
+	auth.ErrInvalidAudience,
+}
+
+// TokenError rejected bearer token, it is ErrUnauthorized with the reason of rejection
+type TokenError struct {
+	Reason string
+}
+
+func newTokenError(err error) *TokenError {
+	for _, reason := range tokenErrorReasons {
+		if errors.Is(err, reason) {
+			return &TokenError{Reason: reason.Error()}
+		}
+	}
+	return &TokenError{Reason: `invalid token`}
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnauthorized, e.Reason)
+}
+
+func (e *TokenError) Is(target error) bool {
+	return target == ErrUnauthorized
+}
+
+// wwwAuthenticate challenge of 401 response, RFC 6750
+func wwwAuthenticate(err error) string {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, tokenErr.Reason)
+	}
+	return `Bearer`
+}
@@ -0,0 +1,104 @@
+package base
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+	"github.com/M-Fisher/companies_api/app/internal/services/idempotency"
+)
+
+const (
+	// IdempotencyKeyHeader key of POST request, retries with the same key get the response of the first request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks stored responses replayed to retries
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	// idempotencyStoreTimeout timeout of storing the response, it is stored even if the client has gone
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// idempotencyMiddleware executes the request with Idempotency-Key once per caller. Only successful responses
+// are stored, so failed requests can be retried with the same key. Keys of unauthenticated requests are ignored.
+func (a *API) idempotencyMiddleware(next JSONHandler) JSONHandler {
+	return func(ctx context.Context, rw http.ResponseWriter, r *http.Request) (interface{}, error) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || a.Srv.Idempotency == nil {
+			return next(ctx, rw, r)
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			return nil, fmt.Errorf("%w: idempotency key is longer than %d characters", ErrBadRequest, maxIdempotencyKeyLen)
+		}
+		user, err := a.VerifyUser(r)
+		if err != nil {
+			// the handler rejects the request
+			return next(ctx, rw, r)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), userKey, user))
+
+		var body []byte
+		if r.Body != nil {
+			body, err = io.ReadAll(r.Body)
+			if err != nil {
+				return nil, fmt.Errorf("%w: failed to read body", ErrBadRequest)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		log := logger.FromContext(ctx)
+		scope := user.TenantID + "|" + a.principal(r)
+		req, err := a.Srv.Idempotency.Begin(ctx, scope, key, requestHash(r, body))
+		switch {
+		case errors.Is(err, idempotency.ErrKeyReused):
+			return nil, fmt.Errorf("%w: %v", ErrUnprocessableEntity, err)
+		case errors.Is(err, idempotency.ErrKeyInProgress):
+			rw.Header().Set("Retry-After", "1")
+			return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+		case err != nil:
+			log.Error("Failed to begin idempotent request", zap.Error(err))
+			return nil, errors.New("server error")
+		case req.Response != nil:
+			rw.Header().Set(IdempotentReplayedHeader, "true")
+			return json.RawMessage(req.Response), nil
+		}
+
+		res, err := next(ctx, rw, r)
+
+		storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyStoreTimeout)
+		defer cancel()
+		if err != nil {
+			if relErr := a.Srv.Idempotency.Release(storeCtx, req); relErr != nil {
+				log.Error("Failed to release idempotency key", zap.Error(relErr))
+			}
+			return res, err
+		}
+		resJS, mErr := json.Marshal(res)
+		if mErr == nil {
+			mErr = a.Srv.Idempotency.Complete(storeCtx, req, resJS)
+		}
+		if mErr != nil {
+			// retries are executed again once the key lock times out
+			log.Error("Failed to store idempotent response", zap.Error(mErr))
+		}
+		return res, nil
+	}
+}
+
+// requestHash identifies method, path and body of the request, so the key can't be reused for another request
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/accounts"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
+
+// AccountsAPI admin API of service accounts
+type AccountsAPI struct {
+	base.API
+}
+
+// SetRoutes initial routing
+func (a *AccountsAPI) SetRoutes() {
+	a.API.SetJSONRoutes(a.routes())
+}
+
+func (a *AccountsAPI) routes() []base.Route {
+	manage := []string{auth.ScopeServiceAccountsManage}
+	return []base.Route{
+		{Path: "", Method: "GET", Handler: a.GetServiceAccounts, Scopes: manage},
+		{Path: "", Method: "POST", Handler: a.CreateServiceAccount, Scopes: manage},
+		{Path: "/{id}/rotate", Method: "POST", Handler: a.RotateSecret, Scopes: manage},
+		{Path: "/{id}/disable", Method: "POST", Handler: a.DisableServiceAccount, Scopes: manage},
+	}
+}
+
+func (a *AccountsAPI) GetServiceAccounts(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "GetServiceAccounts"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	res, err := a.Srv.AccountsService.GetServiceAccounts(ctx)
+	if err != nil {
+		log.Error("Failed to get service accounts", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New(`global error`)
+	}
+	return base.Response{
+		"service_accounts": res,
+	}, nil
+}
+
+// CreateServiceAccount creates account with requested scopes, the account can't be granted scopes the caller doesn't have
+func (a *AccountsAPI) CreateServiceAccount(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "CreateServiceAccount"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	var data models.CreateServiceAccountRequest
+	err = base.DecodeBody(&data, r)
+	if err != nil {
+		log.Error("Failed to decode request body", zap.Error(err))
+		return base.Response{}, errors.New(`incorrect params`)
+	}
+	if err := a.Srv.AuthService.Authorize(user, data.Scopes...); err != nil {
+		log.Info("Service account scopes exceed scopes of the user", zap.Strings("scopes", data.Scopes), zap.Error(err))
+		return nil, base.ErrForbidden
+	}
+
+	res, err := a.Srv.AccountsService.CreateServiceAccount(ctx, data)
+	if err != nil {
+		log.Error("Failed to create service account", zap.Error(err))
+		if errors.Is(err, accounts.ErrInvalidAccount) {
+			return base.Response{
+				"err": err.Error(),
+			}, errors.New(`incorrect params`)
+		}
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New("server error")
+	}
+	log.Info("Service account created", zap.Int64("user_id", user.ID), zap.String("client_id", res.ClientID))
+
+	return base.Response{
+		"service_account": res,
+	}, nil
+}
+
+func (a *AccountsAPI) RotateSecret(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "RotateSecret"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	id, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse service account id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`service account id required`)
+	}
+
+	res, err := a.Srv.AccountsService.RotateSecret(ctx, id)
+	if err != nil {
+		log.Error("Failed to rotate service account secret", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, serviceError(err)
+	}
+	log.Info("Service account secret rotated", zap.Int64("user_id", user.ID), zap.String("client_id", res.ClientID))
+
+	return base.Response{
+		"service_account": res,
+	}, nil
+}
+
+func (a *AccountsAPI) DisableServiceAccount(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "DisableServiceAccount"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	id, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse service account id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`service account id required`)
+	}
+
+	err = a.Srv.AccountsService.DisableServiceAccount(ctx, id)
+	if err != nil {
+		log.Error("Failed to disable service account", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, serviceError(err)
+	}
+	log.Info("Service account disabled", zap.Int64("user_id", user.ID), zap.Int64("service_account_id", id))
+
+	return base.Response{}, nil
+}
+
+func serviceError(err error) error {
+	if errors.Is(err, accounts.ErrAccountNotFound) || errors.Is(err, accounts.ErrAPIKeyNotFound) {
+		return base.ErrNotFound
+	}
+	return err
+}
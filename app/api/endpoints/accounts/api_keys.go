@@ -0,0 +1,125 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/accounts"
+)
+
+// APIKeysAPI API keys of the caller
+type APIKeysAPI struct {
+	base.API
+}
+
+// SetRoutes initial routing, every authenticated user manages own keys
+func (a *APIKeysAPI) SetRoutes() {
+	a.API.SetJSONRoutes([]base.Route{
+		{Path: "", Method: "GET", Handler: a.GetAPIKeys},
+		{Path: "", Method: "POST", Handler: a.CreateAPIKey},
+		{Path: "/{id}", Method: "DELETE", Handler: a.RevokeAPIKey},
+	})
+}
+
+func (a *APIKeysAPI) GetAPIKeys(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "GetAPIKeys"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	res, err := a.Srv.AccountsService.GetAPIKeys(ctx)
+	if err != nil {
+		log.Error("Failed to get api keys", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New(`global error`)
+	}
+	return base.Response{
+		"api_keys": res,
+	}, nil
+}
+
+// CreateAPIKey creates key with requested scopes, the key can't be granted scopes the caller doesn't have.
+// Service accounts can't create keys, the key would keep working after the account is disabled
+func (a *APIKeysAPI) CreateAPIKey(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "CreateAPIKey"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	if user.ClientID != "" {
+		log.Info("Service account can't create api keys", zap.String("client_id", user.ClientID))
+		return nil, base.ErrForbidden
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	var data models.CreateAPIKeyRequest
+	err = base.DecodeBody(&data, r)
+	if err != nil {
+		log.Error("Failed to decode request body", zap.Error(err))
+		return base.Response{}, errors.New(`incorrect params`)
+	}
+	if len(data.Scopes) == 0 {
+		data.Scopes = user.Scopes
+	}
+	if err := a.Srv.AuthService.Authorize(user, data.Scopes...); err != nil {
+		log.Info("Api key scopes exceed scopes of the user", zap.Strings("scopes", data.Scopes), zap.Error(err))
+		return nil, base.ErrForbidden
+	}
+
+	res, err := a.Srv.AccountsService.CreateAPIKey(ctx, data)
+	if err != nil {
+		log.Error("Failed to create api key", zap.Error(err))
+		if errors.Is(err, accounts.ErrInvalidAPIKeyParams) {
+			return base.Response{
+				"err": err.Error(),
+			}, errors.New(`incorrect params`)
+		}
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New("server error")
+	}
+	log.Info("Api key created", zap.Int64("user_id", user.ID), zap.String("prefix", res.Prefix))
+
+	return base.Response{
+		"api_key": res,
+	}, nil
+}
+
+func (a *APIKeysAPI) RevokeAPIKey(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "RevokeAPIKey"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	id, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse api key id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`api key id required`)
+	}
+
+	err = a.Srv.AccountsService.RevokeAPIKey(ctx, id)
+	if err != nil {
+		log.Error("Failed to revoke api key", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, serviceError(err)
+	}
+	log.Info("Api key revoked", zap.Int64("user_id", user.ID), zap.Int64("api_key_id", id))
+
+	return base.Response{}, nil
+}
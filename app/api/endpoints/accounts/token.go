@@ -0,0 +1,99 @@
+package accounts
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/services/accounts"
+)
+
+const grantTypeClientCredentials = `client_credentials`
+
+// TokenAPI OAuth2 token endpoint, RFC 6749
+type TokenAPI struct {
+	base.API
+}
+
+// tokenError error response of token endpoint, RFC 6749 section 5.2
+type tokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// SetRoutes initial routing, token responses are not wrapped like other API responses,
+// requests are rate limited by client IP so client secrets can't be guessed
+func (a *TokenAPI) SetRoutes() {
+	a.Router.HandleFunc("/token", a.Token).Methods("POST")
+}
+
+// Token issues access token to service account by client_credentials grant,
+// client credentials are accepted in Basic authorization header or in the form
+func (a *TokenAPI) Token(rw http.ResponseWriter, r *http.Request) {
+	log := a.Srv.Log.With(zap.String("method", "Token"))
+	if err := a.CheckRateLimit(r.Context(), rw, r, "ip:"+a.ClientIP(r)); err != nil {
+		log.Info("Token requests rate limited", zap.String("ip", a.ClientIP(r)))
+		a.sendToken(rw, http.StatusTooManyRequests, tokenError{
+			Error:       `temporarily_unavailable`,
+			Description: `too many requests`,
+		})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		log.Info("Failed to parse token request", zap.Error(err))
+		a.sendToken(rw, http.StatusBadRequest, tokenError{Error: `invalid_request`})
+		return
+	}
+	if grantType := r.PostForm.Get(`grant_type`); grantType != grantTypeClientCredentials {
+		a.sendToken(rw, http.StatusBadRequest, tokenError{Error: `unsupported_grant_type`})
+		return
+	}
+
+	clientID, clientSecret, basic := r.BasicAuth()
+	if !basic {
+		clientID, clientSecret = r.PostForm.Get(`client_id`), r.PostForm.Get(`client_secret`)
+	}
+	if clientID == "" || clientSecret == "" {
+		a.sendToken(rw, http.StatusBadRequest, tokenError{
+			Error:       `invalid_request`,
+			Description: `client credentials are required`,
+		})
+		return
+	}
+
+	token, err := a.Srv.AccountsService.IssueToken(r.Context(), clientID, clientSecret, strings.Fields(r.PostForm.Get(`scope`)))
+	switch {
+	case err == nil:
+	case errors.Is(err, accounts.ErrInvalidClient):
+		log.Info("Client authentication failed", zap.String("client_id", clientID))
+		if basic {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		}
+		a.sendToken(rw, http.StatusUnauthorized, tokenError{Error: `invalid_client`})
+		return
+	case errors.Is(err, accounts.ErrInvalidScope):
+		a.sendToken(rw, http.StatusBadRequest, tokenError{Error: `invalid_scope`, Description: err.Error()})
+		return
+	default:
+		log.Error("Failed to issue token", zap.String("client_id", clientID), zap.Error(err))
+		a.sendToken(rw, http.StatusInternalServerError, tokenError{Error: `server_error`})
+		return
+	}
+	log.Info("Token issued", zap.String("client_id", clientID), zap.String("scope", token.Scope))
+
+	a.sendToken(rw, http.StatusOK, token)
+}
+
+func (a *TokenAPI) sendToken(rw http.ResponseWriter, status int, res any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.Header().Set("Pragma", "no-cache")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(res); err != nil {
+		a.Srv.Log.Error("Failed to write response", zap.Error(err))
+	}
+}
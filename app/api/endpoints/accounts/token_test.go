@@ -0,0 +1,169 @@
+package accounts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/ratelimit"
+	"github.com/M-Fisher/companies_api/app/internal/server"
+	"github.com/M-Fisher/companies_api/app/internal/services/accounts"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
+
+func newTestServer(accmocks *accounts.MockAccountsService) *server.Server {
+	return &server.Server{
+		Config: &config.Config{
+			JWTSecret: `test`,
+		},
+		AuthService:     auth.NewService(new(auth.MockIPDataProvider)),
+		AccountsService: accmocks,
+		Log:             zap.NewNop(),
+	}
+}
+
+func newTestRouter(accmocks *accounts.MockAccountsService) *mux.Router {
+	return newTestRouterWithServer(newTestServer(accmocks))
+}
+
+func newTestRouterWithServer(srv *server.Server) *mux.Router {
+	router := mux.NewRouter()
+	tokenAPI := &TokenAPI{API: base.API{Srv: srv}}
+	tokenAPI.SetRouter(router.PathPrefix("/auth").Subrouter())
+	tokenAPI.SetRoutes()
+	accountsAPI := &AccountsAPI{API: base.API{Srv: srv}}
+	accountsAPI.SetRouter(router.PathPrefix("/api/service-accounts").Subrouter())
+	accountsAPI.SetRoutes()
+	apiKeysAPI := &APIKeysAPI{API: base.API{Srv: srv}}
+	apiKeysAPI.SetRouter(router.PathPrefix("/api/api-keys").Subrouter())
+	apiKeysAPI.SetRoutes()
+	return router
+}
+
+func newTokenRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest("POST", "/auth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestToken(t *testing.T) {
+	accmocks := new(accounts.MockAccountsService)
+	accmocks.On("IssueToken", mock.Anything, `sa_1`, `secret`, []string{auth.ScopeCompaniesRead}).
+		Return(&models.Token{AccessToken: `token`, TokenType: `Bearer`, ExpiresIn: 900, Scope: auth.ScopeCompaniesRead}, nil)
+	router := newTestRouter(accmocks)
+
+	req := newTokenRequest(url.Values{
+		"grant_type":    {`client_credentials`},
+		"client_id":     {`sa_1`},
+		"client_secret": {`secret`},
+		"scope":         {auth.ScopeCompaniesRead},
+	})
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, `no-store`, res.Header().Get(`Cache-Control`))
+	var got models.Token
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+	assert.Equal(t, models.Token{AccessToken: `token`, TokenType: `Bearer`, ExpiresIn: 900, Scope: auth.ScopeCompaniesRead}, got)
+}
+
+func TestTokenBasicAuth(t *testing.T) {
+	accmocks := new(accounts.MockAccountsService)
+	accmocks.On("IssueToken", mock.Anything, `sa_1`, `wrong`, []string{}).Return(nil, accounts.ErrInvalidClient)
+	router := newTestRouter(accmocks)
+
+	req := newTokenRequest(url.Values{"grant_type": {`client_credentials`}})
+	req.SetBasicAuth(`sa_1`, `wrong`)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, `Basic realm="token"`, res.Header().Get(`WWW-Authenticate`))
+	assert.JSONEq(t, `{"error":"invalid_client"}`, res.Body.String())
+}
+
+func TestTokenInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		form      url.Values
+		issueErr  error
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:      `Unsupported grant type`,
+			form:      url.Values{"grant_type": {`password`}, "client_id": {`sa_1`}, "client_secret": {`secret`}},
+			wantCode:  http.StatusBadRequest,
+			wantError: `unsupported_grant_type`,
+		},
+		{
+			name:      `No credentials`,
+			form:      url.Values{"grant_type": {`client_credentials`}},
+			wantCode:  http.StatusBadRequest,
+			wantError: `invalid_request`,
+		},
+		{
+			name:      `Scope not granted`,
+			form:      url.Values{"grant_type": {`client_credentials`}, "client_id": {`sa_1`}, "client_secret": {`secret`}},
+			issueErr:  accounts.ErrInvalidScope,
+			wantCode:  http.StatusBadRequest,
+			wantError: `invalid_scope`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accmocks := new(accounts.MockAccountsService)
+			accmocks.On("IssueToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tt.issueErr)
+			router := newTestRouter(accmocks)
+
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, newTokenRequest(tt.form))
+
+			assert.Equal(t, tt.wantCode, res.Code)
+			var got tokenError
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+			assert.Equal(t, tt.wantError, got.Error)
+		})
+	}
+}
+
+func TestTokenRateLimited(t *testing.T) {
+	accmocks := new(accounts.MockAccountsService)
+	accmocks.On("IssueToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, accounts.ErrInvalidClient)
+	srv := newTestServer(accmocks)
+	rules, err := ratelimit.ParseRules([]byte(`{"routes": {"POST /auth/token": {"rate": 1, "per": "1m"}}}`))
+	require.NoError(t, err)
+	srv.RateLimiter = ratelimit.NewLimiter(rules, ratelimit.NewMemoryStore())
+	router := newTestRouterWithServer(srv)
+
+	send := func(clientID string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, newTokenRequest(url.Values{
+			"grant_type":    {`client_credentials`},
+			"client_id":     {clientID},
+			"client_secret": {`wrong`},
+		}))
+		return res
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send(`sa_1`).Code)
+	// guessing secrets of other clients from the same address is limited too
+	res := send(`sa_2`)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, `60`, res.Header().Get(`Retry-After`))
+	assert.JSONEq(t, `{"error":"temporarily_unavailable","error_description":"too many requests"}`, res.Body.String())
+	accmocks.AssertNumberOfCalls(t, "IssueToken", 1)
+}
@@ -0,0 +1,116 @@
+package companies
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/companies"
+)
+
+func (a *CompaniesAPI) GetCollaborators(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "GetCollaborators"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	compID, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse company id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`company id required`)
+	}
+
+	res, err := a.Srv.CompaniesService.GetCollaborators(ctx, uint64(compID))
+	if err != nil {
+		log.Error("Failed to get collaborators", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, serviceError(err)
+	}
+	return base.Response{
+		"collaborators": res,
+	}, nil
+}
+
+func (a *CompaniesAPI) SetCollaborator(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "SetCollaborator"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	compID, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse company id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`company id required`)
+	}
+	userID, err := base.GetVarInt(r, `user_id`)
+	if err != nil {
+		log.Error("Failed to parse user id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`user id required`)
+	}
+
+	data := models.SetCollaboratorRequest{
+		Role: companies.CollaboratorRoleEditor,
+	}
+	if r.ContentLength != 0 {
+		err = base.DecodeBody(&data, r)
+		if err != nil {
+			log.Error("Failed to parse request body", zap.Error(err))
+			return base.Response{}, errors.New(`incorrect params`)
+		}
+	}
+
+	err = a.Srv.CompaniesService.AddCollaborator(ctx, uint64(compID), userID, data.Role)
+	if err != nil {
+		log.Error("Failed to add collaborator", zap.Error(err))
+		if errors.Is(err, companies.ErrInvalidRole) {
+			return base.Response{}, errors.New(`incorrect params`)
+		}
+		return base.Response{
+			"err": err.Error(),
+		}, serviceError(err)
+	}
+	return base.Response{}, nil
+}
+
+func (a *CompaniesAPI) DeleteCollaborator(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "DeleteCollaborator"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	compID, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse company id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`company id required`)
+	}
+	userID, err := base.GetVarInt(r, `user_id`)
+	if err != nil {
+		log.Error("Failed to parse user id from request url", zap.Error(err))
+		return base.Response{}, errors.New(`user id required`)
+	}
+
+	err = a.Srv.CompaniesService.RemoveCollaborator(ctx, uint64(compID), userID)
+	if err != nil {
+		log.Error("Failed to remove collaborator", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, serviceError(err)
+	}
+	return base.Response{}, nil
+}
@@ -3,14 +3,20 @@ package companies
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"go.uber.org/zap"
 
 	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
 	"github.com/M-Fisher/companies_api/app/internal/logger"
 	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/policy"
 	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/services/companies"
+	"github.com/M-Fisher/companies_api/app/internal/services/quotas"
 )
 
 // CompaniesAPI - common API struct for companies
@@ -20,16 +26,34 @@ type CompaniesAPI struct {
 
 // SetRoutes initial routing
 func (a *CompaniesAPI) SetRoutes() {
-	a.API.SetJSONHandler("", a.GetCompanies).Methods("GET")
-	a.API.SetJSONHandler("/{id}", a.UpdateCompany).Methods("PUT")
-	a.API.SetJSONHandler("/{id}", a.DeleteCompany).Methods("DELETE")
-	a.API.SetJSONHandler("", a.CreateCompany).Methods("POST")
+	a.API.SetJSONRoutes(a.routes())
+}
+
+// routes endpoints of companies API with scopes required to call them
+func (a *CompaniesAPI) routes() []base.Route {
+	return []base.Route{
+		{Path: "", Method: "GET", Handler: a.GetCompanies, Scopes: []string{auth.ScopeCompaniesRead}},
+		{Path: "/{id}", Method: "PUT", Handler: a.UpdateCompany, Scopes: []string{auth.ScopeCompaniesWrite}},
+		{Path: "/{id}", Method: "DELETE", Handler: a.DeleteCompany, Scopes: []string{auth.ScopeCompaniesDelete}},
+		{Path: "", Method: "POST", Handler: a.CreateCompany, Scopes: []string{auth.ScopeCompaniesWrite}, Idempotent: true},
+		{Path: "/import", Method: "POST", Handler: a.ImportCompanies, Scopes: []string{auth.ScopeCompaniesWrite}, Idempotent: true},
+		{Path: "/{id}/collaborators", Method: "GET", Handler: a.GetCollaborators, Scopes: []string{auth.ScopeCompaniesRead}},
+		{Path: "/{id}/collaborators/{user_id}", Method: "PUT", Handler: a.SetCollaborator, Scopes: []string{auth.ScopeCompaniesWrite}},
+		{Path: "/{id}/collaborators/{user_id}", Method: "DELETE", Handler: a.DeleteCollaborator, Scopes: []string{auth.ScopeCompaniesWrite}},
+	}
 }
 
 func (a *CompaniesAPI) GetCompanies(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
 	log := logger.FromContext(ctx).With(zap.String("method", "GetCompanies"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
 	var data models.GetCompanyRequest
-	err := base.DecodeQuery(&data, r)
+	err = base.DecodeQuery(&data, r)
 	if err != nil {
 		log.Error("Failed to decode query params", zap.Error(err))
 		return base.Response{}, errors.New(`incorrect params`)
@@ -44,6 +68,9 @@ func (a *CompaniesAPI) GetCompanies(ctx context.Context, rw http.ResponseWriter,
 	})
 	if err != nil {
 		log.Error("Failed to get companies", zap.Error(err))
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			return base.Response{}, serviceError(err)
+		}
 		return base.Response{
 			"err": err.Error(),
 		}, errors.New(`global error`)
@@ -55,12 +82,33 @@ func (a *CompaniesAPI) GetCompanies(ctx context.Context, rw http.ResponseWriter,
 
 func (a *CompaniesAPI) UpdateCompany(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
 	log := logger.FromContext(ctx).With(zap.String("method", "UpdateCompany"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
 	compID, err := base.GetVarInt(r, `id`)
 	if err != nil {
 		log.Error("Failed to parse company id from request url", zap.Error(err))
 		return base.Response{}, errors.New(`company id required`)
 	}
 
+	resource, err := a.companyResource(ctx, user, uint64(compID))
+	if err != nil {
+		log.Error("Failed to get company owner", zap.Error(err))
+		return base.Response{}, serviceError(err)
+	}
+	clientIP := a.ClientIP(r)
+	stat, err := a.Srv.AuthService.IsActionAllowed(ctx, auth.ActionCompanyUpdate, user, clientIP, resource)
+	if err != nil {
+		log.Error("Failed to verify user location", zap.String("remote_addr", clientIP), zap.Error(err))
+	}
+	if !a.Srv.Config.DevMode && !stat {
+		log.Info("Action is not allowed due to region restrictions", zap.String("remote_addr", clientIP))
+		return nil, base.ErrUnauthorized
+	}
+
 	var data models.Company
 	err = base.DecodeBody(&data, r)
 	if err != nil {
@@ -73,7 +121,7 @@ func (a *CompaniesAPI) UpdateCompany(ctx context.Context, rw http.ResponseWriter
 		log.Error("Failed to update company", zap.Error(err))
 		return base.Response{
 			"err": err.Error(),
-		}, err
+		}, serviceError(err)
 	}
 	return base.Response{
 		"company": newComp,
@@ -82,49 +130,58 @@ func (a *CompaniesAPI) UpdateCompany(ctx context.Context, rw http.ResponseWriter
 
 func (a *CompaniesAPI) DeleteCompany(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
 	log := logger.FromContext(ctx).With(zap.String("method", "DeleteCompany"))
-	_, err := a.VerifyUser(r)
+	user, err := a.VerifyUser(r)
 	if err != nil {
 		log.Error("Failed to verify user")
 		return nil, err
 	}
-	stat, err := a.Srv.AuthService.IsActionAllowed(auth.ActionCompanyDelete, r.RemoteAddr)
+	ctx = identity.ToContext(ctx, user.Identity())
+	compID, err := base.GetVarInt(r, `id`)
+	if err != nil {
+		log.Error("Failed to parse company id from request", zap.Error(err))
+		return base.Response{}, errors.New(`company id required`)
+	}
+
+	resource, err := a.companyResource(ctx, user, uint64(compID))
+	if err != nil {
+		log.Error("Failed to get company owner", zap.Error(err))
+		return base.Response{}, serviceError(err)
+	}
+	clientIP := a.ClientIP(r)
+	stat, err := a.Srv.AuthService.IsActionAllowed(ctx, auth.ActionCompanyDelete, user, clientIP, resource)
 	if err != nil {
 		log.Error("Failed to verify user location", zap.Error(err))
 	}
 	if !a.Srv.Config.DevMode && !stat {
-		log.Info("Action is not allowed due to region restrictions", zap.String("remote_addr", r.RemoteAddr))
+		log.Info("Action is not allowed due to region restrictions", zap.String("remote_addr", clientIP))
 		return nil, base.ErrUnauthorized
 	}
 
-	compID, err := base.GetVarInt(r, `id`)
-	if err != nil {
-		log.Error("Failed to parse company id from request", zap.Error(err))
-		return base.Response{}, errors.New(`company id required`)
-	}
-
 	err = a.Srv.CompaniesService.DeleteCompany(ctx, uint64(compID))
 	if err != nil {
 		log.Error("Failed to delete company", zap.Error(err))
 		return base.Response{
 			"err": err.Error(),
-		}, err
+		}, serviceError(err)
 	}
 	return base.Response{}, nil
 }
 
 func (a *CompaniesAPI) CreateCompany(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
 	log := logger.FromContext(ctx).With(zap.String("method", "CreateCompany"))
-	_, err := a.VerifyUser(r)
+	user, err := a.VerifyUser(r)
 	if err != nil {
 		log.Error("Failed to verify user", zap.Error(err))
 		return nil, err
 	}
-	stat, err := a.Srv.AuthService.IsActionAllowed(auth.ActionCompanyCreate, r.RemoteAddr)
+	ctx = identity.ToContext(ctx, user.Identity())
+	clientIP := a.ClientIP(r)
+	stat, err := a.Srv.AuthService.IsActionAllowed(ctx, auth.ActionCompanyCreate, user, clientIP, policy.Resource{})
 	if err != nil {
-		log.Error("Failed to verify user location", zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+		log.Error("Failed to verify user location", zap.String("remote_addr", clientIP), zap.Error(err))
 	}
 	if !a.Srv.Config.DevMode && !stat {
-		log.Info("Action is not allowed due to region restrictions", zap.String("remote_addr", r.RemoteAddr))
+		log.Info("Action is not allowed due to region restrictions", zap.String("remote_addr", clientIP))
 		return nil, base.ErrUnauthorized
 	}
 	var userData models.Company
@@ -137,6 +194,9 @@ func (a *CompaniesAPI) CreateCompany(ctx context.Context, rw http.ResponseWriter
 	compID, err := a.Srv.CompaniesService.CreateCompany(ctx, userData)
 	if err != nil {
 		log.Error("Failed to create company", zap.Error(err))
+		if errors.Is(err, quotas.ErrQuotaExceeded) || errors.Is(err, companies.ErrCompanyCodeExists) {
+			return base.Response{}, serviceError(err)
+		}
 		return base.Response{
 			"err": err.Error(),
 		}, errors.New("server error")
@@ -146,3 +206,83 @@ func (a *CompaniesAPI) CreateCompany(ctx context.Context, rw http.ResponseWriter
 		"company_id": compID,
 	}, nil
 }
+
+func (a *CompaniesAPI) ImportCompanies(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "ImportCompanies"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+	clientIP := a.ClientIP(r)
+	stat, err := a.Srv.AuthService.IsActionAllowed(ctx, auth.ActionCompanyCreate, user, clientIP, policy.Resource{})
+	if err != nil {
+		log.Error("Failed to verify user location", zap.String("remote_addr", clientIP), zap.Error(err))
+	}
+	if !a.Srv.Config.DevMode && !stat {
+		log.Info("Action is not allowed due to region restrictions", zap.String("remote_addr", clientIP))
+		return nil, base.ErrUnauthorized
+	}
+	var data []models.Company
+	err = base.DecodeBody(&data, r)
+	if err != nil {
+		log.Error("Failed to decode request body", zap.Error(err))
+		return base.Response{}, errors.New(`incorrect params`)
+	}
+
+	res, err := a.Srv.CompaniesService.ImportCompanies(ctx, data)
+	if err != nil {
+		log.Error("Failed to import companies", zap.Error(err))
+		if errors.Is(err, quotas.ErrQuotaExceeded) {
+			// batches imported before the quota was exceeded stay committed
+			return nil, &base.PartialError{
+				Err: serviceError(err),
+				Payload: base.Response{
+					"company_ids": res.IDs,
+					"conflicts":   res.Conflicts,
+				},
+			}
+		}
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New("server error")
+	}
+
+	return base.Response{
+		"company_ids": res.IDs,
+		"conflicts":   res.Conflicts,
+	}, nil
+}
+
+// companyResource company the action is applied to, for policy decisions
+func (a *CompaniesAPI) companyResource(ctx context.Context, user *auth.JWTUser, compID uint64) (policy.Resource, error) {
+	ownerID, err := a.Srv.CompaniesService.GetCompanyOwner(ctx, compID)
+	if err != nil {
+		return policy.Resource{}, err
+	}
+	return policy.Resource{
+		ID:       strconv.FormatUint(compID, 10),
+		OwnerID:  ownerID,
+		TenantID: user.TenantID,
+	}, nil
+}
+
+// serviceError maps access and quota errors of companies service to API errors,
+// stored companies over quota are forbidden while other quotas can be retried once they reset
+func serviceError(err error) error {
+	var exceeded *quotas.ExceededError
+	switch {
+	case errors.As(err, &exceeded) && exceeded.Metric == quotas.MetricCompanies:
+		return fmt.Errorf("%w: %v", base.ErrForbidden, exceeded)
+	case errors.As(err, &exceeded):
+		return fmt.Errorf("%w: %v", base.ErrTooManyRequests, exceeded)
+	case errors.Is(err, companies.ErrForbidden):
+		return base.ErrForbidden
+	case errors.Is(err, companies.ErrCompanyNotFound):
+		return base.ErrNotFound
+	case errors.Is(err, companies.ErrCompanyCodeExists):
+		return fmt.Errorf("%w: %v", base.ErrConflict, err)
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/services/revocation"
+)
+
+// TokensAPI revocation of JWT
+type TokensAPI struct {
+	base.API
+}
+
+// SetRoutes initial routing
+func (a *TokensAPI) SetRoutes() {
+	a.API.SetJSONRoutes([]base.Route{
+		{Path: "/revoke", Method: "POST", Handler: a.RevokeTokens},
+	})
+}
+
+// RevokeTokens revokes tokens of the caller, admins can revoke tokens of any user of their tenant
+func (a *TokensAPI) RevokeTokens(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "RevokeTokens"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	var data models.RevokeTokensRequest
+	err = base.DecodeBody(&data, r)
+	if err != nil {
+		log.Error("Failed to decode request body", zap.Error(err))
+		return base.Response{}, errors.New(`incorrect params`)
+	}
+
+	switch {
+	case data.UserID != 0:
+		if data.UserID != user.ID && !user.Identity().HasRole(auth.RoleAdmin) {
+			return nil, base.ErrForbidden
+		}
+		before := time.Now()
+		if data.IssuedBefore != nil && data.IssuedBefore.Before(before) {
+			before = *data.IssuedBefore
+		}
+		err = a.Srv.Revocations.RevokeUserTokens(ctx, data.UserID, before)
+		if err != nil {
+			log.Error("Failed to revoke user tokens", zap.Error(err))
+			return base.Response{
+				"err": err.Error(),
+			}, errors.New("server error")
+		}
+		log.Info("User tokens revoked", zap.Int64("user_id", user.ID), zap.Int64("revoked_user_id", data.UserID), zap.Time("issued_before", before))
+		return base.Response{}, nil
+	case data.Token != "":
+		target, err := a.ParseToken(ctx, data.Token)
+		if err != nil {
+			log.Info("Failed to parse revoked token", zap.Error(err))
+			return base.Response{}, errors.New(`incorrect params`)
+		}
+		if target.TenantID != user.TenantID || (target.ID != user.ID && !user.Identity().HasRole(auth.RoleAdmin)) {
+			return nil, base.ErrForbidden
+		}
+		return a.revokeToken(ctx, log, user, target)
+	case user.JWT == "":
+		// the caller is authenticated by API key, which is revoked by its own endpoint
+		return base.Response{}, errors.New(`incorrect params`)
+	}
+	return a.revokeToken(ctx, log, user, user)
+}
+
+func (a *TokensAPI) revokeToken(ctx context.Context, log *zap.Logger, user, target *auth.JWTUser) (any, error) {
+	err := a.Srv.Revocations.RevokeToken(ctx, target)
+	if err != nil {
+		log.Error("Failed to revoke token", zap.Error(err))
+		if errors.Is(err, revocation.ErrNoTokenID) {
+			return base.Response{
+				"err": err.Error(),
+			}, errors.New(`incorrect params`)
+		}
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New("server error")
+	}
+	log.Info("Token revoked", zap.Int64("user_id", user.ID), zap.Int64("revoked_user_id", target.ID), zap.String("jti", target.TokenID))
+	return base.Response{}, nil
+}
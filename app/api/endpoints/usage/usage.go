@@ -0,0 +1,47 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/api/base"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+)
+
+// UsageAPI usage of the caller against its quota
+type UsageAPI struct {
+	base.API
+}
+
+// SetRoutes initial routing
+func (a *UsageAPI) SetRoutes() {
+	a.API.SetJSONRoutes([]base.Route{
+		{Path: "", Method: "GET", Handler: a.GetUsage},
+	})
+}
+
+// GetUsage reports usage of the caller in current windows, quota of the API key is reported for requests with one
+func (a *UsageAPI) GetUsage(ctx context.Context, rw http.ResponseWriter, r *http.Request) (any, error) {
+	log := logger.FromContext(ctx).With(zap.String("method", "GetUsage"))
+	user, err := a.VerifyUser(r)
+	if err != nil {
+		log.Error("Failed to verify user", zap.Error(err))
+		return nil, err
+	}
+	ctx = identity.ToContext(ctx, user.Identity())
+
+	usage, err := a.Srv.Quotas.GetUsage(ctx)
+	if err != nil {
+		log.Error("Failed to get usage", zap.Error(err))
+		return base.Response{
+			"err": err.Error(),
+		}, errors.New("server error")
+	}
+	return base.Response{
+		"usage": usage,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/M-Fisher/companies_api/app/internal/metrics"
+)
+
+// ErrCircuitOpen returned without asking the provider while it is failing
+var ErrCircuitOpen = errors.New("circuit is open")
+
+// breakerEvents counts state changes (open, half_open, closed) and rejected lookups by provider name
+var breakerEvents = metrics.NewCounter("ipdata_circuit_breaker")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type lookupResult int
+
+const (
+	lookupSucceeded lookupResult = iota
+	lookupFailed
+	// lookupCancelled by the caller, it says nothing about the provider
+	lookupCancelled
+)
+
+// Breaker stops asking the provider for cooldown after the number of consecutive failures.
+// After cooldown a single lookup probes the provider, its success closes the circuit.
+// Unknown locations and lookups cancelled by the caller aren't failures.
+type Breaker struct {
+	name     string
+	provider Provider
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failed   int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker opens the circuit after failures consecutive failures of the provider
+func NewBreaker(name string, provider Provider, failures int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		name:     name,
+		provider: provider,
+		failures: failures,
+		cooldown: cooldown,
+		now:      time.Now,
+	}
+}
+
+func (b *Breaker) GetRequestLocation(ctx context.Context, ip string) (string, error) {
+	if !b.allow() {
+		breakerEvents.Add(b.name+"_rejected", 1)
+		return ``, fmt.Errorf("%s: %w", b.name, ErrCircuitOpen)
+	}
+	country, err := b.provider.GetRequestLocation(ctx, ip)
+	b.record(lookupResultOf(ctx, err))
+	return country, err
+}
+
+func lookupResultOf(ctx context.Context, err error) lookupResult {
+	switch {
+	case err == nil, errors.Is(err, ErrLocationNotFound):
+		return lookupSucceeded
+	case ctx.Err() != nil:
+		return lookupCancelled
+	}
+	return lookupFailed
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record result of the lookup. Cancelled lookup doesn't change the state,
+// a cancelled probe lets the next lookup probe again.
+func (b *Breaker) record(result lookupResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerHalfOpen:
+		b.probing = false
+		switch result {
+		case lookupFailed:
+			b.open()
+		case lookupSucceeded:
+			b.failed = 0
+			b.setState(breakerClosed)
+		}
+	case breakerClosed:
+		switch result {
+		case lookupCancelled:
+			return
+		case lookupSucceeded:
+			b.failed = 0
+			return
+		}
+		b.failed++
+		if b.failed >= b.failures {
+			b.open()
+		}
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.setState(breakerOpen)
+}
+
+func (b *Breaker) setState(state breakerState) {
+	b.state = state
+	switch state {
+	case breakerOpen:
+		breakerEvents.Add(b.name+"_open", 1)
+	case breakerHalfOpen:
+		breakerEvents.Add(b.name+"_half_open", 1)
+	case breakerClosed:
+		breakerEvents.Add(b.name+"_closed", 1)
+	}
+}
@@ -0,0 +1,116 @@
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
+
+func TestBreakerGetRequestLocation(t *testing.T) {
+	upstreamErr := errors.New(`service unavailable`)
+	provider := new(auth.MockIPDataProvider)
+	failing := provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(``, upstreamErr)
+	provider.On("GetRequestLocation", mock.Anything, `10.0.0.1`).Return(``, ErrLocationNotFound)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(`ipapi`, provider, 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// unknown locations aren't failures
+	for i := 0; i < 3; i++ {
+		_, err := b.GetRequestLocation(context.Background(), `10.0.0.1`)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := b.GetRequestLocation(context.Background(), `31.153.10.1`)
+		assert.ErrorIs(t, err, upstreamErr)
+	}
+	_, err := b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 5)
+
+	// failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	_, err = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.ErrorIs(t, err, upstreamErr)
+	_, err = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// successful probe closes it
+	failing.Unset()
+	provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(`CY`, nil)
+	now = now.Add(time.Minute)
+	country, err := b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.NoError(t, err)
+	assert.Equal(t, `CY`, country)
+	_, err = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.NoError(t, err)
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	release := make(chan struct{})
+	calls := make(chan struct{}, 2)
+	b := NewBreaker(`ipapi`, providerFunc(func(ip string) (string, error) {
+		calls <- struct{}{}
+		<-release
+		return `CY`, nil
+	}), 1, time.Minute)
+	b.state = breakerOpen
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	}()
+	<-calls
+	_, err := b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.ErrorIs(t, err, ErrCircuitOpen, `only one probe runs while half open`)
+	close(release)
+	<-done
+	assert.Equal(t, breakerClosed, b.state)
+}
+
+func TestBreakerCancelledLookups(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	provider := new(auth.MockIPDataProvider)
+	provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(``, context.Canceled)
+	b := NewBreaker(`ipapi`, provider, 1, time.Minute)
+
+	_, _ = b.GetRequestLocation(ctx, `31.153.10.1`)
+	_, err := b.GetRequestLocation(ctx, `31.153.10.1`)
+	assert.ErrorIs(t, err, context.Canceled, `cancelled lookups don't open the circuit`)
+}
+
+func TestBreakerCancelledLookupsKeepState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	upstreamErr := errors.New(`service unavailable`)
+	provider := new(auth.MockIPDataProvider)
+	provider.On("GetRequestLocation", ctx, `31.153.10.1`).Return(``, context.Canceled)
+	provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(``, upstreamErr)
+	b := NewBreaker(`ipapi`, provider, 2, time.Minute)
+
+	// cancelled lookup doesn't reset consecutive failures
+	_, _ = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	_, _ = b.GetRequestLocation(ctx, `31.153.10.1`)
+	assert.Equal(t, 1, b.failed)
+	_, _ = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.Equal(t, breakerOpen, b.state)
+
+	// cancelled probe keeps the circuit half open for the next probe
+	b.openedAt = b.openedAt.Add(-time.Minute)
+	_, err := b.GetRequestLocation(ctx, `31.153.10.1`)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, breakerHalfOpen, b.state)
+	assert.False(t, b.probing)
+	_, err = b.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.ErrorIs(t, err, upstreamErr)
+	assert.Equal(t, breakerOpen, b.state)
+}
@@ -0,0 +1,130 @@
+package ipdata
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/M-Fisher/companies_api/app/internal/metrics"
+)
+
+// cacheLookups counts lookups by result: hit, error_hit (cached error), miss, shared (joined running lookup)
+// and evicted entries
+var cacheLookups = metrics.NewCounter("ipdata_cache_lookups")
+
+// Cache caches countries resolved by the provider. Errors are cached for ErrorTTL,
+// concurrent lookups of the same ip share one request to the provider. The shared request
+// isn't cancelled with the request which started it, a caller stops waiting when its ctx is done.
+type Cache struct {
+	provider Provider
+	ttl      time.Duration
+	errorTTL time.Duration
+	size     int
+	now      func() time.Time
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru most recently used entries in front
+	lru *list.List
+}
+
+type cacheEntry struct {
+	ip        string
+	country   string
+	err       error
+	expiresAt time.Time
+}
+
+// NewCache caches up to size countries for ttl, errors for errorTTL (0 disables caching of errors)
+func NewCache(provider Provider, ttl, errorTTL time.Duration, size int) *Cache {
+	return &Cache{
+		provider: provider,
+		ttl:      ttl,
+		errorTTL: errorTTL,
+		size:     size,
+		now:      time.Now,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (c *Cache) GetRequestLocation(ctx context.Context, ip string) (string, error) {
+	if entry, ok := c.get(ip); ok {
+		if entry.err != nil {
+			cacheLookups.Add("error_hit", 1)
+		} else {
+			cacheLookups.Add("hit", 1)
+		}
+		return entry.country, entry.err
+	}
+	cacheLookups.Add("miss", 1)
+
+	ch := c.group.DoChan(ip, func() (interface{}, error) {
+		country, err := c.provider.GetRequestLocation(context.Background(), ip)
+		c.set(ip, country, err)
+		return country, err
+	})
+	select {
+	case <-ctx.Done():
+		return ``, ctx.Err()
+	case res := <-ch:
+		if res.Shared {
+			cacheLookups.Add("shared", 1)
+		}
+		return res.Val.(string), res.Err
+	}
+}
+
+func (c *Cache) get(ip string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[ip]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(el)
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(el)
+	return *entry, true
+}
+
+func (c *Cache) set(ip, country string, err error) {
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.errorTTL
+	}
+	// the circuit breaker fails fast already, the lookup is retried once the circuit closes
+	if errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+	if ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cacheEntry{ip: ip, country: country, err: err, expiresAt: c.now().Add(ttl)}
+	if el, ok := c.entries[ip]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[ip] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		cacheLookups.Add("evicted", 1)
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).ip)
+}
@@ -0,0 +1,140 @@
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
+
+type providerFunc func(ip string) (string, error)
+
+func (f providerFunc) GetRequestLocation(_ context.Context, ip string) (string, error) {
+	return f(ip)
+}
+
+func TestCacheGetRequestLocation(t *testing.T) {
+	provider := new(auth.MockIPDataProvider)
+	provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(`CY`, nil)
+	provider.On("GetRequestLocation", mock.Anything, `10.0.0.1`).Return(``, ErrLocationNotFound)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCache(provider, time.Hour, time.Minute, 10)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		country, err := c.GetRequestLocation(context.Background(), `31.153.10.1`)
+		assert.NoError(t, err)
+		assert.Equal(t, `CY`, country)
+		_, err = c.GetRequestLocation(context.Background(), `10.0.0.1`)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	}
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 2)
+
+	now = now.Add(2 * time.Minute)
+	_, _ = c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	_, _ = c.GetRequestLocation(context.Background(), `10.0.0.1`)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 3)
+
+	now = now.Add(time.Hour)
+	_, _ = c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 4)
+}
+
+func TestCacheErrorsNotCached(t *testing.T) {
+	provider := new(auth.MockIPDataProvider)
+	provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(``, errors.New(`rate limited`))
+	c := NewCache(provider, time.Hour, 0, 10)
+
+	_, _ = c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	_, err := c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.Error(t, err)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 2)
+}
+
+func TestCacheEviction(t *testing.T) {
+	provider := new(auth.MockIPDataProvider)
+	provider.On("GetRequestLocation", mock.Anything, `1.1.1.1`).Return(`AU`, nil)
+	provider.On("GetRequestLocation", mock.Anything, `2.2.2.2`).Return(`FR`, nil)
+	provider.On("GetRequestLocation", mock.Anything, `3.3.3.3`).Return(`US`, nil)
+	c := NewCache(provider, time.Hour, time.Minute, 2)
+
+	_, _ = c.GetRequestLocation(context.Background(), `1.1.1.1`)
+	_, _ = c.GetRequestLocation(context.Background(), `2.2.2.2`)
+	// 1.1.1.1 becomes most recently used, 2.2.2.2 is evicted
+	_, _ = c.GetRequestLocation(context.Background(), `1.1.1.1`)
+	_, _ = c.GetRequestLocation(context.Background(), `3.3.3.3`)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 3)
+
+	_, _ = c.GetRequestLocation(context.Background(), `1.1.1.1`)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 3)
+	_, _ = c.GetRequestLocation(context.Background(), `2.2.2.2`)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 4)
+	assert.Equal(t, 2, c.lru.Len())
+}
+
+func TestCacheConcurrentLookups(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c := NewCache(providerFunc(func(ip string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return `CY`, nil
+	}), time.Hour, time.Minute, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			country, err := c.GetRequestLocation(context.Background(), `31.153.10.1`)
+			assert.NoError(t, err)
+			assert.Equal(t, `CY`, country)
+		}()
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestCacheCancelledLookup(t *testing.T) {
+	release := make(chan struct{})
+	c := NewCache(providerFunc(func(ip string) (string, error) {
+		<-release
+		return `CY`, nil
+	}), time.Hour, time.Minute, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.GetRequestLocation(ctx, `31.153.10.1`)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the lookup isn't abandoned and fills the cache for the next callers
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, ok := c.get(`31.153.10.1`)
+		return ok
+	}, time.Second, time.Millisecond)
+	country, err := c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.NoError(t, err)
+	assert.Equal(t, `CY`, country)
+}
+
+func TestCacheCircuitOpenNotCached(t *testing.T) {
+	provider := new(auth.MockIPDataProvider)
+	provider.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(``, ErrCircuitOpen)
+	c := NewCache(provider, time.Hour, time.Minute, 10)
+
+	_, _ = c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	_, _ = c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	provider.AssertNumberOfCalls(t, "GetRequestLocation", 2)
+}
@@ -0,0 +1,50 @@
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/M-Fisher/companies_api/app/internal/metrics"
+)
+
+// Provider resolves ISO code of the country of ip
+type Provider interface {
+	GetRequestLocation(ctx context.Context, ip string) (string, error)
+}
+
+// providerLookups counts lookups of chained providers by provider name and result, e.g. mmdb_ok, ipapi_error
+var providerLookups = metrics.NewCounter("ipdata_provider_lookups")
+
+// Named provider, the name is used in errors and metrics
+type Named struct {
+	Name string
+	Provider
+}
+
+// Chain asks providers in order until one of them resolves the country
+type Chain []Named
+
+func (c Chain) GetRequestLocation(ctx context.Context, ip string) (string, error) {
+	var err error
+	for _, p := range c {
+		if ctx.Err() != nil {
+			return ``, ctx.Err()
+		}
+		country, perr := p.GetRequestLocation(ctx, ip)
+		if perr == nil {
+			providerLookups.Add(p.Name+"_ok", 1)
+			return country, nil
+		}
+		providerLookups.Add(p.Name+"_error", 1)
+		if err == nil {
+			err = fmt.Errorf("%s: %w", p.Name, perr)
+		} else {
+			err = fmt.Errorf("%v; %s: %w", err, p.Name, perr)
+		}
+	}
+	if err == nil {
+		return ``, errors.New("no IP data providers")
+	}
+	return ``, err
+}
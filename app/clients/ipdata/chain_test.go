@@ -0,0 +1,58 @@
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
+
+func TestChainGetRequestLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		primary      []interface{}
+		fallback     []interface{}
+		want         string
+		wantErr      string
+		fallbackUsed bool
+	}{
+		{name: `First provider succeeded`, primary: []interface{}{`CY`, nil}, want: `CY`},
+		{name: `First provider failed`, primary: []interface{}{``, ErrLocationNotFound}, fallback: []interface{}{`GR`, nil}, want: `GR`, fallbackUsed: true},
+		{
+			name:         `All providers failed`,
+			primary:      []interface{}{``, ErrLocationNotFound},
+			fallback:     []interface{}{``, errors.New(`rate limited`)},
+			wantErr:      `mmdb: location is not found; ipapi: rate limited`,
+			fallbackUsed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := new(auth.MockIPDataProvider)
+			primary.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(tt.primary...)
+			fallback := new(auth.MockIPDataProvider)
+			if tt.fallbackUsed {
+				fallback.On("GetRequestLocation", mock.Anything, `31.153.10.1`).Return(tt.fallback...)
+			}
+			c := Chain{{Name: `mmdb`, Provider: primary}, {Name: `ipapi`, Provider: fallback}}
+
+			got, err := c.GetRequestLocation(context.Background(), `31.153.10.1`)
+			if tt.wantErr != `` {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			fallback.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEmptyChain(t *testing.T) {
+	_, err := Chain{}.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.Error(t, err)
+}
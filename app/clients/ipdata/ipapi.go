@@ -1,8 +1,8 @@
 package ipdata
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,24 +10,30 @@ import (
 )
 
 type client struct {
-	requestTimeout time.Duration
+	httpClient *http.Client
 }
 
+// NewClient creates ipapi.co client, connections are kept alive between lookups
 func NewClient(requestTimeout time.Duration) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = requestTimeout
+	transport.ResponseHeaderTimeout = requestTimeout
 	return &client{
-		requestTimeout: requestTimeout,
+		httpClient: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+		},
 	}
 }
 
-func (c *client) GetRequestLocation(ip string) (string, error) {
-	r, err := http.NewRequest(`GET`, fmt.Sprintf(`https://ipapi.co/%s/json/`, ip), nil)
+func (c *client) GetRequestLocation(ctx context.Context, ip string) (string, error) {
+	r, err := http.NewRequestWithContext(ctx, `GET`, fmt.Sprintf(`https://ipapi.co/%s/json/`, ip), nil)
 	if err != nil {
 		return ``, err
 	}
-	client := &http.Client{
-		Timeout: c.requestTimeout,
-	}
-	resp, err := client.Do(r)
+	resp, err := c.httpClient.Do(r)
 	if err != nil {
 		return ``, err
 	}
@@ -51,7 +57,7 @@ func (c *client) GetRequestLocation(ip string) (string, error) {
 		return ``, err
 	}
 	if responseData.Error {
-		return ``, errors.New(responseData.Reason)
+		return ``, fmt.Errorf("%s: %w", responseData.Reason, ErrLocationNotFound)
 	}
 	return responseData.CountryCode, nil
 }
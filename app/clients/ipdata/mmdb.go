@@ -0,0 +1,144 @@
+package ipdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"go.uber.org/zap"
+)
+
+// ErrLocationNotFound returned when the database has no country of the ip
+var ErrLocationNotFound = errors.New("location is not found")
+
+// mmdbRecord fields of GeoLite2/DB-IP country and city databases used for lookups
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// MMDBClient resolves country of ip from a local MaxMind format database (GeoLite2, DB-IP).
+// The database is reloaded when the file is modified.
+type MMDBClient struct {
+	file    string
+	log     *zap.Logger
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+// NewMMDBClient loads the database from the file
+func NewMMDBClient(file string, log *zap.Logger) (*MMDBClient, error) {
+	c := &MMDBClient{
+		file: file,
+		log:  log.With(zap.String("mmdb", file)),
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the database from the file, previous database is kept on failure
+func (c *MMDBClient) Reload() error {
+	info, err := os.Stat(c.file)
+	if err != nil {
+		return fmt.Errorf("failed to load mmdb: %w", err)
+	}
+	// the file is read into memory, so it can be replaced while lookups are running
+	data, err := os.ReadFile(c.file)
+	if err != nil {
+		return fmt.Errorf("failed to load mmdb: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return fmt.Errorf("failed to load mmdb: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reader = reader
+	c.modTime = info.ModTime()
+	return nil
+}
+
+// Watch reloads the database on interval if the file is modified. Returned function stops watching.
+func (c *MMDBClient) Watch(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				reloaded, err := c.reloadIfModified()
+				if err != nil {
+					c.log.Error("Failed to reload mmdb, previous database is kept", zap.Error(err))
+					continue
+				}
+				if reloaded {
+					c.log.Info("Mmdb reloaded", zap.String("database_type", c.databaseType()))
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+func (c *MMDBClient) reloadIfModified() (bool, error) {
+	info, err := os.Stat(c.file)
+	if err != nil {
+		return false, fmt.Errorf("failed to check mmdb: %w", err)
+	}
+	c.mu.RLock()
+	modified := info.ModTime().After(c.modTime)
+	c.mu.RUnlock()
+	if !modified {
+		return false, nil
+	}
+	return true, c.Reload()
+}
+
+func (c *MMDBClient) databaseType() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.reader.Metadata.DatabaseType
+}
+
+// GetRequestLocation returns ISO code of the country of ip
+func (c *MMDBClient) GetRequestLocation(_ context.Context, ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ``, fmt.Errorf("invalid ip address: %q", ip)
+	}
+
+	c.mu.RLock()
+	reader := c.reader
+	c.mu.RUnlock()
+
+	var record mmdbRecord
+	if err := reader.Lookup(parsed, &record); err != nil {
+		return ``, fmt.Errorf("mmdb lookup failed: %w", err)
+	}
+	if record.Country.ISOCode != `` {
+		return record.Country.ISOCode, nil
+	}
+	if record.RegisteredCountry.ISOCode != `` {
+		return record.RegisteredCountry.ISOCode, nil
+	}
+	return ``, fmt.Errorf("%s: %w", ip, ErrLocationNotFound)
+}
@@ -0,0 +1,175 @@
+package ipdata
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// mmdbNode record of the search tree: child node index, data offset or none
+type mmdbNode [2]struct {
+	node int
+	data int
+	set  bool
+}
+
+// writeTestMMDB writes IPv4 database with country iso codes of networks
+func writeTestMMDB(t *testing.T, file string, countries map[string]string) {
+	t.Helper()
+	nodes := []mmdbNode{{}}
+	var data []byte
+	for cidr, country := range countries {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ones, _ := network.Mask.Size()
+		offset := len(data)
+		data = append(data, mmdbMap(1)...)
+		data = append(data, mmdbString("country")...)
+		data = append(data, mmdbMap(1)...)
+		data = append(data, mmdbString("iso_code")...)
+		data = append(data, mmdbString(country)...)
+
+		current := 0
+		for i := 0; i < ones; i++ {
+			bit := (network.IP.To4()[i/8] >> (7 - i%8)) & 1
+			if i == ones-1 {
+				nodes[current][bit].data = offset
+				nodes[current][bit].set = true
+				break
+			}
+			if nodes[current][bit].node == 0 {
+				nodes = append(nodes, mmdbNode{})
+				nodes[current][bit].node = len(nodes) - 1
+			}
+			current = nodes[current][bit].node
+		}
+	}
+
+	var db []byte
+	nodeCount := uint32(len(nodes))
+	for _, n := range nodes {
+		for _, r := range n {
+			value := nodeCount
+			switch {
+			case r.set:
+				value = nodeCount + 16 + uint32(r.data)
+			case r.node != 0:
+				value = uint32(r.node)
+			}
+			db = binary.BigEndian.AppendUint32(db, value)
+		}
+	}
+	db = append(db, make([]byte, 16)...)
+	db = append(db, data...)
+	db = append(db, "\xAB\xCD\xEFMaxMind.com"...)
+	db = append(db, mmdbMap(6)...)
+	db = append(db, mmdbString("node_count")...)
+	db = append(db, mmdbUint(6, uint64(nodeCount))...)
+	db = append(db, mmdbString("record_size")...)
+	db = append(db, mmdbUint(5, 32)...)
+	db = append(db, mmdbString("ip_version")...)
+	db = append(db, mmdbUint(5, 4)...)
+	db = append(db, mmdbString("database_type")...)
+	db = append(db, mmdbString("Test-Country")...)
+	db = append(db, mmdbString("binary_format_major_version")...)
+	db = append(db, mmdbUint(5, 2)...)
+	db = append(db, mmdbString("binary_format_minor_version")...)
+	db = append(db, mmdbUint(5, 0)...)
+
+	require.NoError(t, os.WriteFile(file, db, 0o600))
+}
+
+func mmdbMap(size int) []byte {
+	return []byte{7<<5 | byte(size)}
+}
+
+func mmdbString(s string) []byte {
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func mmdbUint(typ byte, v uint64) []byte {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return append([]byte{typ<<5 | byte(len(b))}, b...)
+}
+
+func TestMMDBClientGetRequestLocation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "country.mmdb")
+	writeTestMMDB(t, file, map[string]string{
+		"31.153.0.0/16": "CY",
+		"8.8.8.0/24":    "US",
+	})
+	c, err := NewMMDBClient(file, zap.NewNop())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		ip      string
+		want    string
+		wantErr error
+	}{
+		{name: `Country of network`, ip: `31.153.10.1`, want: `CY`},
+		{name: `Another network`, ip: `8.8.8.8`, want: `US`},
+		{name: `IPv4-mapped IPv6 address`, ip: `::ffff:8.8.8.8`, want: `US`},
+		{name: `Unknown network`, ip: `9.9.9.9`, wantErr: ErrLocationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.GetRequestLocation(context.Background(), tt.ip)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = c.GetRequestLocation(context.Background(), `31.153.10.1:8080`)
+	assert.Error(t, err)
+}
+
+func TestMMDBClientReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "country.mmdb")
+	writeTestMMDB(t, file, map[string]string{"31.153.0.0/16": "CY"})
+	c, err := NewMMDBClient(file, zap.NewNop())
+	require.NoError(t, err)
+
+	reloaded, err := c.reloadIfModified()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	writeTestMMDB(t, file, map[string]string{"31.153.0.0/16": "GR"})
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+	reloaded, err = c.reloadIfModified()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	country, err := c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.NoError(t, err)
+	assert.Equal(t, `GR`, country)
+
+	require.NoError(t, os.WriteFile(file, []byte(`broken`), 0o600))
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(file, modTime, modTime))
+	_, err = c.reloadIfModified()
+	assert.Error(t, err)
+	country, err = c.GetRequestLocation(context.Background(), `31.153.10.1`)
+	assert.NoError(t, err)
+	assert.Equal(t, `GR`, country, `previous database is kept`)
+}
+
+func TestNewMMDBClientMissingFile(t *testing.T) {
+	_, err := NewMMDBClient(filepath.Join(t.TempDir(), "missing.mmdb"), zap.NewNop())
+	assert.Error(t, err)
+}
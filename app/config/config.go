@@ -9,10 +9,42 @@ import (
 type Config struct {
 	Port                string        `envconfig:"PORT" default:":8080"`
 	IPApiRequestTimeout time.Duration `envconfig:"IPAPI_REQUEST_TIMEOUT" default:"2s"`
-	Postgres            DB            `envconfig:"POSTGRES"`
-	Kafka               Kafka         `envconfig:"KAFKA"`
-	DevMode             bool          `envconfig:"DEVELOPMENT_MODE" default:"false"`
-	JWTSecret           string        `envconfig:"JWT_SECRET" default:"test"`
+	IPData              IPData        `envconfig:"IP_DATA"`
+	// TrustedProxies addresses or CIDRs of proxies whose ClientIPHeader is trusted
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+	// ClientIPHeader the header trusted proxies set: X-Forwarded-For, Forwarded or X-Real-IP
+	ClientIPHeader string `envconfig:"CLIENT_IP_HEADER" default:"X-Forwarded-For"`
+	Postgres       DB     `envconfig:"POSTGRES"`
+	Kafka          Kafka  `envconfig:"KAFKA"`
+	DevMode        bool   `envconfig:"DEVELOPMENT_MODE" default:"false"`
+	// JWTSecret secret of HMAC signed tokens, empty value disables them
+	JWTSecret string `envconfig:"JWT_SECRET" default:"test"`
+	// JWTIssuer and JWTAudience required iss and aud claims of tokens, not checked if empty
+	JWTIssuer   string `envconfig:"JWT_ISSUER"`
+	JWTAudience string `envconfig:"JWT_AUDIENCE"`
+	// JWTMaxAge maximum age of tokens by iat claim, 0 disables the check
+	JWTMaxAge time.Duration `envconfig:"JWT_MAX_AGE"`
+	// JWTLeeway clock skew allowed checking exp, nbf and iat claims
+	JWTLeeway time.Duration `envconfig:"JWT_LEEWAY" default:"30s"`
+	// JWTRequireExpiry rejects tokens without exp claim, disable only while issuers migrate
+	JWTRequireExpiry bool `envconfig:"JWT_REQUIRE_EXPIRY" default:"true"`
+	// JWTLegacyScopes scopes of tokens which carry neither roles nor scopes, such tokens are granted nothing by default
+	JWTLegacyScopes []string `envconfig:"JWT_LEGACY_SCOPES"`
+	// ServiceTokenTTL lifetime of tokens issued to service accounts
+	ServiceTokenTTL time.Duration `envconfig:"SERVICE_TOKEN_TTL" default:"15m"`
+	// TokenRevocationRefreshInterval how often revocations made by other instances are loaded
+	TokenRevocationRefreshInterval time.Duration `envconfig:"TOKEN_REVOCATION_REFRESH_INTERVAL" default:"30s"`
+	// JWKSURL URL or file path of JWKS verifying asymmetric (RS256, ES256, EdDSA) tokens
+	JWKSURL             string        `envconfig:"JWKS_URL"`
+	JWKSRefreshInterval time.Duration `envconfig:"JWKS_REFRESH_INTERVAL" default:"15m"`
+	JWKSRequestTimeout  time.Duration `envconfig:"JWKS_REQUEST_TIMEOUT" default:"5s"`
+	TLS                 TLS           `envconfig:"TLS"`
+	RegionPolicy        RegionPolicy  `envconfig:"REGION_POLICY"`
+	AccessPolicy        AccessPolicy  `envconfig:"ACCESS_POLICY"`
+	IPPolicy            IPPolicy      `envconfig:"IP_POLICY"`
+	RateLimit           RateLimit     `envconfig:"RATE_LIMIT"`
+	Quota               Quota         `envconfig:"QUOTA"`
+	Idempotency         Idempotency   `envconfig:"IDEMPOTENCY"`
 }
 
 type DB struct {
@@ -23,6 +55,109 @@ type DB struct {
 	MaxIdleConnTime time.Duration `envconfig:"MAX_IDLE_CONN_TIME" default:"5m"`
 	MaxConns        int           `envconfig:"MAX_CONNS" default:"20"`
 	ConnMaxLifetime time.Duration `envconfig:"CONN_MAX_LIFETIME" default:"10m"`
+	// ReplicaHosts read-only hosts used for queries outside of transactions
+	ReplicaHosts          []string      `envconfig:"REPLICA_HOSTS"`
+	ReplicaHealthInterval time.Duration `envconfig:"REPLICA_HEALTH_INTERVAL" default:"5s"`
+	TxMaxRetries          int           `envconfig:"TX_MAX_RETRIES" default:"3"`
+	TxRetryBackoff        time.Duration `envconfig:"TX_RETRY_BACKOFF" default:"50ms"`
+	// StatementTimeout postgres statement_timeout for every connection, 0 disables it
+	StatementTimeout time.Duration `envconfig:"STATEMENT_TIMEOUT" default:"30s"`
+	ReadTimeout      time.Duration `envconfig:"READ_TIMEOUT" default:"2s"`
+	SearchTimeout    time.Duration `envconfig:"SEARCH_TIMEOUT" default:"5s"`
+	WriteTimeout     time.Duration `envconfig:"WRITE_TIMEOUT" default:"3s"`
+	// BulkTimeout overrides statement timeout for bulk loading transactions
+	BulkTimeout time.Duration `envconfig:"BULK_TIMEOUT" default:"10m"`
+	// SlowQueryThreshold queries running longer are logged, 0 disables logging
+	SlowQueryThreshold time.Duration `envconfig:"SLOW_QUERY_THRESHOLD" default:"500ms"`
+}
+
+// TLS server listens with TLS if certificate is set
+type TLS struct {
+	CertFile string `envconfig:"CERT_FILE"`
+	KeyFile  string `envconfig:"KEY_FILE"`
+	// ClientCAFile CA bundle verifying client certificates, client certificates are not requested if empty
+	ClientCAFile string `envconfig:"CLIENT_CA_FILE"`
+	// RequireClientCert rejects connections without client certificate, otherwise other credentials are accepted too
+	RequireClientCert bool `envconfig:"REQUIRE_CLIENT_CERT" default:"false"`
+	// ClientPrincipalsFile JSON mapping of client certificates to users
+	ClientPrincipalsFile string `envconfig:"CLIENT_PRINCIPALS_FILE"`
+}
+
+// IPData source of countries of requests
+type IPData struct {
+	// Provider mmdb (local MaxMind format database) or ipapi (ipapi.co)
+	Provider string `envconfig:"PROVIDER" default:"mmdb"`
+	// MMDBFile GeoLite2 or DB-IP country or city database, reloaded when modified
+	MMDBFile       string        `envconfig:"MMDB_FILE" default:"/usr/share/GeoIP/GeoLite2-Country.mmdb"`
+	ReloadInterval time.Duration `envconfig:"RELOAD_INTERVAL" default:"1m"`
+	// Fallback asks ipapi.co when mmdb lookup fails
+	Fallback bool `envconfig:"FALLBACK" default:"false"`
+	// CacheTTL lifetime of cached countries, 0 disables the cache
+	CacheTTL time.Duration `envconfig:"CACHE_TTL" default:"1h"`
+	// CacheErrorTTL lifetime of cached lookup errors, 0 disables caching of errors
+	CacheErrorTTL time.Duration `envconfig:"CACHE_ERROR_TTL" default:"1m"`
+	// CacheSize maximum number of cached addresses, least recently used are evicted
+	CacheSize int `envconfig:"CACHE_SIZE" default:"10000"`
+	// BreakerFailures consecutive ipapi.co failures stopping lookups for BreakerCooldown, 0 disables the breaker
+	BreakerFailures int           `envconfig:"BREAKER_FAILURES" default:"5"`
+	BreakerCooldown time.Duration `envconfig:"BREAKER_COOLDOWN" default:"30s"`
+}
+
+// RegionPolicy source of countries allowed per action, built-in policy is used if none is set
+type RegionPolicy struct {
+	// File JSON policy file, reloaded when modified
+	File string `envconfig:"FILE"`
+	// Rules JSON policy used when File is not set
+	Rules          string        `envconfig:"RULES"`
+	ReloadInterval time.Duration `envconfig:"RELOAD_INTERVAL" default:"30s"`
+}
+
+// AccessPolicy rules of policy engine evaluated together with region policy
+type AccessPolicy struct {
+	// File JSON policy file, reloaded when modified
+	File           string        `envconfig:"FILE"`
+	ReloadInterval time.Duration `envconfig:"RELOAD_INTERVAL" default:"30s"`
+}
+
+// IPPolicy networks allowed and denied per action, checked before geolocation
+type IPPolicy struct {
+	// File JSON policy file, reloaded when modified
+	File string `envconfig:"FILE"`
+	// Rules JSON policy used when File is not set
+	Rules          string        `envconfig:"RULES"`
+	ReloadInterval time.Duration `envconfig:"RELOAD_INTERVAL" default:"30s"`
+	// AllowPrivate private and loopback addresses are not limited, for development environments
+	AllowPrivate bool `envconfig:"ALLOW_PRIVATE" default:"false"`
+}
+
+// RateLimit limits of requests by route and principal, default limits are used if none are set
+type RateLimit struct {
+	// File JSON limits
+	File string `envconfig:"FILE"`
+	// Rules JSON limits used when File is not set
+	Rules string `envconfig:"RULES"`
+	// RedisURL Redis compatible server sharing limits between instances, limits are kept in memory if empty
+	RedisURL string `envconfig:"REDIS_URL"`
+}
+
+// Quota usage quotas of tenants and API keys, usage is not limited if none are set
+type Quota struct {
+	// File JSON quota plans
+	File string `envconfig:"FILE"`
+	// Plans JSON quota plans used when File is not set
+	Plans string `envconfig:"PLANS"`
+	// CleanupInterval how often counters of past windows are deleted
+	CleanupInterval time.Duration `envconfig:"CLEANUP_INTERVAL" default:"1h"`
+}
+
+// Idempotency stored responses of POST requests with Idempotency-Key header
+type Idempotency struct {
+	// TTL how long responses are replayed to retries
+	TTL time.Duration `envconfig:"TTL" default:"24h"`
+	// LockTimeout after which a request which didn't complete can be retried, it must exceed the longest request
+	LockTimeout time.Duration `envconfig:"LOCK_TIMEOUT" default:"15m"`
+	// CleanupInterval how often expired keys are deleted
+	CleanupInterval time.Duration `envconfig:"CLEANUP_INTERVAL" default:"1h"`
 }
 
 type Kafka struct {
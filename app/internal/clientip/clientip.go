@@ -0,0 +1,143 @@
+// Package clientip resolves IP address of the client of a request behind trusted proxies
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Headers the client address can be taken from
+const (
+	HeaderXForwardedFor = "X-Forwarded-For"
+	HeaderForwarded     = "Forwarded"
+	HeaderXRealIP       = "X-Real-IP"
+)
+
+// Resolver takes the client address from the single header the proxies set only if the request came
+// from a trusted proxy, otherwise the address of the connection is used. Other forwarding headers are
+// ignored: proxies pass them from the client as is, so they can be forged.
+type Resolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// NewResolver trusts proxies in the networks, CIDR or single address, and the header they set,
+// X-Forwarded-For if empty
+func NewResolver(trustedProxies []string, header string) (*Resolver, error) {
+	r := &Resolver{header: HeaderXForwardedFor}
+	if header != "" {
+		r.header = http.CanonicalHeaderKey(header)
+	}
+	switch r.header {
+	case HeaderXForwardedFor, HeaderForwarded, http.CanonicalHeaderKey(HeaderXRealIP):
+	default:
+		return nil, fmt.Errorf("unsupported client ip header: %q", header)
+	}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %q", proxy)
+			}
+			r.trusted = append(r.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// ClientIP returns address of the client. Forwarding headers are read from right to left,
+// addresses of trusted proxies are skipped and the first untrusted one is the client.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	remote := ParseIP(req.RemoteAddr)
+	if remote == nil {
+		return req.RemoteAddr
+	}
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+
+	chain := r.forwardedFor(req.Header)
+	client := remote
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := ParseIP(chain[i])
+		if ip == nil {
+			// obfuscated or malformed address, hops before it can't be verified
+			break
+		}
+		client = ip
+		if !r.isTrusted(ip) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (r *Resolver) isTrusted(ip net.IP) bool {
+	for _, network := range r.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor addresses of the configured header, the nearest hop is the last
+func (r *Resolver) forwardedFor(h http.Header) []string {
+	var chain []string
+	switch r.header {
+	case HeaderForwarded:
+		// RFC 7239
+		for _, value := range h.Values(HeaderForwarded) {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(name, "for") {
+						chain = append(chain, strings.Trim(value, `"`))
+					}
+				}
+			}
+		}
+	case HeaderXForwardedFor:
+		for _, value := range h.Values(HeaderXForwardedFor) {
+			for _, addr := range strings.Split(value, ",") {
+				chain = append(chain, strings.TrimSpace(addr))
+			}
+		}
+	default:
+		// X-Real-IP is set, not appended, by the proxy, the last value is the one it set
+		if values := h.Values(r.header); len(values) > 0 {
+			chain = append(chain, values[len(values)-1])
+		}
+	}
+	return chain
+}
+
+// ParseIP parses address with optional port, brackets and zone: 1.2.3.4, 1.2.3.4:80, ::1, [::1]:80, fe80::1%eth0.
+// IPv4-mapped IPv6 addresses are returned as IPv4, nil is returned for invalid address.
+func ParseIP(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	ip := net.ParseIP(addr)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
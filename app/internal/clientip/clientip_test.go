@@ -0,0 +1,124 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolverClientIP(t *testing.T) {
+	proxies := []string{`10.0.0.0/8`, `fd00::/8`, `192.168.1.1`}
+
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: `Direct IPv4 with port`, remoteAddr: `203.0.113.7:5123`, want: `203.0.113.7`},
+		{name: `Direct IPv6 with port`, remoteAddr: `[2001:db8::1]:5123`, want: `2001:db8::1`},
+		{name: `Direct without port`, remoteAddr: `203.0.113.7`, want: `203.0.113.7`},
+		{name: `IPv4-mapped IPv6`, remoteAddr: `[::ffff:203.0.113.7]:5123`, want: `203.0.113.7`},
+		{
+			name:       `Headers of untrusted client are ignored`,
+			remoteAddr: `203.0.113.7:5123`,
+			headers:    map[string]string{`X-Forwarded-For`: `198.51.100.1`, `X-Real-IP`: `198.51.100.2`},
+			want:       `203.0.113.7`,
+		},
+		{
+			name:       `X-Forwarded-For from trusted proxy`,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`X-Forwarded-For`: `198.51.100.1`},
+			want:       `198.51.100.1`,
+		},
+		{
+			name:       `Spoofed hops before untrusted address are ignored`,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`X-Forwarded-For`: `1.2.3.4, 198.51.100.1, 10.0.0.5`},
+			want:       `198.51.100.1`,
+		},
+		{
+			name:       `All hops are trusted`,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`X-Forwarded-For`: `10.0.0.3, 10.0.0.5`},
+			want:       `10.0.0.3`,
+		},
+		{
+			name:       `IPv6 with port in X-Forwarded-For`,
+			remoteAddr: `[fd00::1]:5123`,
+			headers:    map[string]string{`X-Forwarded-For`: `[2001:db8::5]:4431`},
+			want:       `2001:db8::5`,
+		},
+		{
+			name:       `Forwarded header`,
+			header:     HeaderForwarded,
+			remoteAddr: `192.168.1.1:5123`,
+			headers:    map[string]string{`Forwarded`: `for=198.51.100.1;proto=https, for="[2001:db8::5]:4431";by=10.0.0.2`},
+			want:       `2001:db8::5`,
+		},
+		{
+			name:       `Forged Forwarded header is ignored`,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`Forwarded`: `for=198.51.100.1`, `X-Forwarded-For`: `198.51.100.2`},
+			want:       `198.51.100.2`,
+		},
+		{
+			name:       `Forged X-Real-IP header is ignored`,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`X-Real-IP`: `198.51.100.1`},
+			want:       `10.0.0.1`,
+		},
+		{
+			name:       `Forged X-Forwarded-For header is ignored`,
+			header:     HeaderForwarded,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`Forwarded`: `for=198.51.100.1`, `X-Forwarded-For`: `198.51.100.2`},
+			want:       `198.51.100.1`,
+		},
+		{
+			name:       `Obfuscated hop`,
+			header:     HeaderForwarded,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`Forwarded`: `for=198.51.100.1, for=_hidden, for=10.0.0.4`},
+			want:       `10.0.0.4`,
+		},
+		{
+			name:       `X-Real-IP from trusted proxy`,
+			header:     HeaderXRealIP,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`X-Real-IP`: `198.51.100.1`},
+			want:       `198.51.100.1`,
+		},
+		{
+			name:       `Invalid X-Real-IP`,
+			header:     HeaderXRealIP,
+			remoteAddr: `10.0.0.1:5123`,
+			headers:    map[string]string{`X-Real-IP`: `unknown`},
+			want:       `10.0.0.1`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewResolver(proxies, tt.header)
+			require.NoError(t, err)
+			req := httptest.NewRequest(`GET`, `/`, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, r.ClientIP(req))
+		})
+	}
+}
+
+func TestNewResolverInvalidProxy(t *testing.T) {
+	_, err := NewResolver([]string{`10.0.0.0/33`}, "")
+	assert.Error(t, err)
+	_, err = NewResolver([]string{`proxy.local`}, "")
+	assert.Error(t, err)
+	_, err = NewResolver([]string{`10.0.0.1`}, `True-Client-IP`)
+	assert.Error(t, err)
+}
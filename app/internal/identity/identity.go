@@ -0,0 +1,37 @@
+package identity
+
+import "context"
+
+// DefaultTenant tenant of users whose credentials don't specify one
+const DefaultTenant = `default`
+
+// Identity authenticated caller of the request
+type Identity struct {
+	UserID   int64
+	TenantID string
+	Roles    []string
+	// APIKeyID key the request is authenticated with, 0 for other credentials
+	APIKeyID int64
+}
+
+func (id Identity) HasRole(role string) bool {
+	for _, r := range id.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type identityContextType int
+
+const identityKey identityContextType = iota
+
+func ToContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey, id)
+}
+
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sort"
+	"sync"
+)
+
+// DurationBuckets default buckets in seconds for latency histograms
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram counts observed values in cumulative buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func NewHistogram(buckets []float64) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Histogram{
+		buckets: b,
+		counts:  make([]uint64, len(b)),
+	}
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += v
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+type histogramSnapshot struct {
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+	Buckets map[string]uint64 `json:"buckets"`
+}
+
+func (h *Histogram) snapshot() histogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := histogramSnapshot{
+		Count:   h.count,
+		Sum:     h.sum,
+		Buckets: make(map[string]uint64, len(h.buckets)+1),
+	}
+	for i, b := range h.buckets {
+		s.Buckets[formatBucket(b)] = h.counts[i]
+	}
+	s.Buckets["+Inf"] = h.count
+	return s
+}
+
+// String implements expvar.Var
+func (h *Histogram) String() string {
+	res, _ := json.Marshal(h.snapshot())
+	return string(res)
+}
+
+// HistogramVec set of histograms with the same buckets split by label (e.g. query name)
+type HistogramVec struct {
+	mu         sync.RWMutex
+	buckets    []float64
+	histograms map[string]*Histogram
+}
+
+// NewHistogramVec creates HistogramVec and publishes it to expvar by name
+func NewHistogramVec(name string, buckets []float64) *HistogramVec {
+	v := &HistogramVec{
+		buckets:    buckets,
+		histograms: map[string]*Histogram{},
+	}
+	expvar.Publish(name, v)
+	return v
+}
+
+func (v *HistogramVec) Observe(label string, value float64) {
+	v.mu.RLock()
+	h, ok := v.histograms[label]
+	v.mu.RUnlock()
+	if !ok {
+		v.mu.Lock()
+		if h, ok = v.histograms[label]; !ok {
+			h = NewHistogram(v.buckets)
+			v.histograms[label] = h
+		}
+		v.mu.Unlock()
+	}
+	h.Observe(value)
+}
+
+// String implements expvar.Var
+func (v *HistogramVec) String() string {
+	v.mu.RLock()
+	res := make(map[string]histogramSnapshot, len(v.histograms))
+	for label, h := range v.histograms {
+		res[label] = h.snapshot()
+	}
+	v.mu.RUnlock()
+	js, _ := json.Marshal(res)
+	return string(js)
+}
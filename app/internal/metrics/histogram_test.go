@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	h := NewHistogram([]float64{1, 0.1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	assert.JSONEq(t, `{"count":3,"sum":3.55,"buckets":{"0.1":1,"1":2,"+Inf":3}}`, h.String())
+}
+
+func TestHistogramVec_Observe(t *testing.T) {
+	v := NewHistogramVec("test_histogram_vec", []float64{1})
+	v.Observe("a", 0.5)
+	v.Observe("b", 2)
+
+	assert.JSONEq(t, `{
+		"a":{"count":1,"sum":0.5,"buckets":{"1":1,"+Inf":1}},
+		"b":{"count":1,"sum":2,"buckets":{"1":0,"+Inf":1}}
+	}`, v.String())
+}
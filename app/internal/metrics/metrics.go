@@ -0,0 +1,16 @@
+// Package metrics contains service metrics published via expvar (/debug/vars)
+package metrics
+
+import (
+	"expvar"
+	"strconv"
+)
+
+// NewCounter creates counters map published to expvar by name
+func NewCounter(name string) *expvar.Map {
+	return expvar.NewMap(name)
+}
+
+func formatBucket(b float64) string {
+	return strconv.FormatFloat(b, 'g', -1, 64)
+}
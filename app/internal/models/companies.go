@@ -15,4 +15,26 @@ type Company struct {
 	Country string `json:"country"`
 	Website string `json:"website"`
 	Phone   string `json:"phone"`
+	OwnerID int64  `json:"owner_id,omitempty"`
+}
+
+type Collaborator struct {
+	UserID int64  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type SetCollaboratorRequest struct {
+	Role string `json:"role"`
+}
+
+type ImportResult struct {
+	IDs       []uint64         `json:"ids"`
+	Conflicts []ImportConflict `json:"conflicts"`
+}
+
+// ImportConflict company skipped during import, Row is its index in the request
+type ImportConflict struct {
+	Row    int    `json:"row"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
 }
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+type ServiceAccount struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// ServiceAccountCredentials account with its secret, the secret is shown only once
+type ServiceAccountCredentials struct {
+	ServiceAccount
+	ClientSecret string `json:"client_secret"`
+}
+
+// Token access token response of OAuth2 token endpoint
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// APIKey credential of the user sent in X-API-Key header
+type APIKey struct {
+	ID int64 `json:"id"`
+	// Prefix public part of the key identifying it
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyWithSecret created key, the key is shown only once
+type APIKeyWithSecret struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// RevokeTokensRequest revokes the token, all tokens of the user issued before the time
+// or the token of the caller if neither is set
+type RevokeTokensRequest struct {
+	Token        string     `json:"token"`
+	UserID       int64      `json:"user_id"`
+	IssuedBefore *time.Time `json:"issued_before"`
+}
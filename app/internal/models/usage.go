@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Usage of the caller against its quota, counters of unlimited requests and bulk operations are omitted
+type Usage struct {
+	TenantID string `json:"tenant_id"`
+	// Subject whose quota applies, "tenant" or "api_key:<id>"
+	Subject        string        `json:"subject"`
+	Requests       *UsageCounter `json:"requests,omitempty"`
+	BulkOperations *UsageCounter `json:"bulk_operations,omitempty"`
+	Companies      UsageCounter  `json:"companies"`
+}
+
+// UsageCounter usage within the current window, Limit is nil if usage is not limited
+type UsageCounter struct {
+	Used  int64  `json:"used"`
+	Limit *int64 `json:"limit"`
+	// ResetAt when the window ends, nil for usage which is not reset
+	ResetAt *time.Time `json:"reset_at,omitempty"`
+}
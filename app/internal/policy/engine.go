@@ -0,0 +1,274 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+)
+
+var errUnknownCountry = errors.New("country of the request is unknown")
+
+type Subject struct {
+	ID       int64
+	TenantID string
+	Roles    []string
+	Scopes   []string
+}
+
+type Resource struct {
+	Type     string
+	ID       string
+	OwnerID  int64
+	TenantID string
+}
+
+type Request struct {
+	IP   string
+	Time time.Time
+}
+
+// Input of authorization decision
+type Input struct {
+	Subject  Subject
+	Action   string
+	Resource Resource
+	Request  Request
+}
+
+// Decision result of evaluation, Reasons explain which rules decided it
+type Decision struct {
+	Allowed bool
+	Reasons []string
+	// Err error of attribute resolution which led to denial
+	Err error
+}
+
+// Locator resolves country of the request IP
+type Locator interface {
+	GetRequestLocation(ctx context.Context, ip string) (string, error)
+}
+
+// Engine evaluates inputs against the current policy and writes audit log of every decision
+type Engine struct {
+	mu      sync.RWMutex
+	policy  *Policy
+	locator Locator
+}
+
+func NewEngine(p *Policy, locator Locator) *Engine {
+	return &Engine{
+		policy:  p,
+		locator: locator,
+	}
+}
+
+// SetPolicy replaces policy used for new decisions
+func (e *Engine) SetPolicy(p *Policy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = p
+}
+
+func (e *Engine) getPolicy() *Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.policy
+}
+
+// Evaluate decides whether the input is allowed. Deny rules which can't be evaluated
+// (e.g. country lookup failed) deny the action, such allow rules don't allow it, unless the rule fails open.
+func (e *Engine) Evaluate(ctx context.Context, in Input) Decision {
+	d := e.evaluate(&attributes{ctx: ctx, input: in, locator: e.locator})
+	Audit(ctx, in, d)
+	return d
+}
+
+func (e *Engine) evaluate(attrs *attributes) Decision {
+	var (
+		rules   = e.getPolicy().Rules
+		reasons []string
+		evalErr error
+	)
+	for _, rule := range rules {
+		if rule.Effect != EffectDeny || !matchesAction(rule.Actions, attrs.input.Action) {
+			continue
+		}
+		matched, _, err := rule.matches(attrs)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", rule.ID, err))
+			evalErr = err
+			continue
+		}
+		if matched {
+			reasons = append(reasons, rule.ID)
+		}
+	}
+	if len(reasons) > 0 {
+		return Decision{Allowed: false, Reasons: reasons, Err: evalErr}
+	}
+
+	for _, rule := range rules {
+		if rule.Effect != EffectAllow || !matchesAction(rule.Actions, attrs.input.Action) {
+			continue
+		}
+		matched, failedOpen, err := rule.matches(attrs)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", rule.ID, err))
+			evalErr = err
+			continue
+		}
+		if matched && failedOpen != nil {
+			return Decision{Allowed: true, Reasons: []string{fmt.Sprintf("%s: %s, failed open", rule.ID, failedOpen)}}
+		}
+		if matched {
+			return Decision{Allowed: true, Reasons: []string{rule.ID}}
+		}
+	}
+	return Decision{Allowed: false, Reasons: append(reasons, "no matching allow rule"), Err: evalErr}
+}
+
+// Audit writes audit log of the decision, decisions made outside of the engine are logged by it too
+func Audit(ctx context.Context, in Input, d Decision) {
+	logger.FromContext(ctx).Info("Authorization decision",
+		zap.String("log_type", "audit"),
+		zap.Int64("subject_id", in.Subject.ID),
+		zap.String("subject_tenant_id", in.Subject.TenantID),
+		zap.String("action", in.Action),
+		zap.String("resource_type", in.Resource.Type),
+		zap.String("resource_id", in.Resource.ID),
+		zap.String("request_ip", in.Request.IP),
+		zap.Bool("allowed", d.Allowed),
+		zap.Strings("reasons", d.Reasons),
+	)
+}
+
+func matchesAction(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == Any || a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether all conditions of the rule match, failedOpen is the error of the country
+// which was considered matching because the rule fails open
+func (r Rule) matches(attrs *attributes) (matched bool, failedOpen error, err error) {
+	for _, c := range r.Conditions {
+		values, err := attrs.get(c.Attr)
+		if err != nil {
+			if !r.FailOpen || c.Attr != AttrRequestCountry {
+				return false, nil, err
+			}
+			// unresolved country doesn't deny the action and is allowed, other conditions still apply
+			if r.Effect == EffectDeny {
+				return false, nil, nil
+			}
+			failedOpen = err
+			continue
+		}
+		if !c.matches(values) {
+			return false, nil, nil
+		}
+	}
+	return true, failedOpen, nil
+}
+
+func (c Condition) matches(values []string) bool {
+	switch c.Op {
+	case OpIn:
+		return intersects(values, c.Values)
+	case OpNotIn:
+		return !intersects(values, c.Values)
+	case OpBetween:
+		from, _ := strconv.Atoi(c.Values[0])
+		to, _ := strconv.Atoi(c.Values[1])
+		for _, v := range values {
+			n, err := strconv.Atoi(v)
+			if err == nil && n >= from && n <= to {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func intersects(values, list []string) bool {
+	for _, l := range list {
+		if l == Any {
+			return true
+		}
+		for _, v := range values {
+			if v == l {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attributes resolves input attributes, the country is looked up once and only if a rule needs it
+type attributes struct {
+	ctx        context.Context
+	input      Input
+	locator    Locator
+	country    string
+	countryErr error
+	located    bool
+}
+
+func (a *attributes) get(attr string) ([]string, error) {
+	in := a.input
+	switch attr {
+	case AttrSubjectID:
+		return []string{strconv.FormatInt(in.Subject.ID, 10)}, nil
+	case AttrSubjectTenantID:
+		return []string{in.Subject.TenantID}, nil
+	case AttrSubjectRoles:
+		return in.Subject.Roles, nil
+	case AttrSubjectScopes:
+		return in.Subject.Scopes, nil
+	case AttrAction:
+		return []string{in.Action}, nil
+	case AttrResourceType:
+		return []string{in.Resource.Type}, nil
+	case AttrResourceID:
+		return []string{in.Resource.ID}, nil
+	case AttrResourceOwnerID:
+		return []string{strconv.FormatInt(in.Resource.OwnerID, 10)}, nil
+	case AttrResourceTenant:
+		return []string{in.Resource.TenantID}, nil
+	case AttrRequestIP:
+		return []string{in.Request.IP}, nil
+	case AttrRequestCountry:
+		country, err := a.requestCountry()
+		if err != nil {
+			return nil, err
+		}
+		return []string{country}, nil
+	case AttrRequestHour:
+		return []string{strconv.Itoa(in.Request.Time.UTC().Hour())}, nil
+	case AttrRequestWeekday:
+		return []string{strconv.Itoa(int(in.Request.Time.UTC().Weekday()))}, nil
+	}
+	return nil, fmt.Errorf("unknown attribute %q", attr)
+}
+
+func (a *attributes) requestCountry() (string, error) {
+	if !a.located {
+		a.located = true
+		if a.locator == nil {
+			a.countryErr = errUnknownCountry
+		} else {
+			a.country, a.countryErr = a.locator.GetRequestLocation(a.ctx, a.input.Request.IP)
+		}
+	}
+	return a.country, a.countryErr
+}
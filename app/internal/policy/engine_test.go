@@ -0,0 +1,209 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+)
+
+type testLocator map[string]string
+
+func (l testLocator) GetRequestLocation(_ context.Context, ip string) (string, error) {
+	if country, ok := l[ip]; ok {
+		return country, nil
+	}
+	return "", errors.New(`unknown ip`)
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	p := &Policy{Rules: []Rule{
+		{
+			ID:      `night`,
+			Effect:  EffectDeny,
+			Actions: []string{Any},
+			Conditions: []Condition{
+				{Attr: AttrSubjectRoles, Op: OpNotIn, Values: []string{`admin`}},
+				{Attr: AttrRequestHour, Op: OpBetween, Values: []string{`0`, `5`}},
+			},
+		},
+		{
+			ID:      `cy`,
+			Effect:  EffectAllow,
+			Actions: []string{`company_create`},
+			Conditions: []Condition{
+				{Attr: AttrRequestCountry, Op: OpIn, Values: []string{`CY`}},
+			},
+		},
+		{
+			ID:      `blocked-country`,
+			Effect:  EffectDeny,
+			Actions: []string{`company_delete`},
+			Conditions: []Condition{
+				{Attr: AttrRequestCountry, Op: OpIn, Values: []string{`US`}},
+			},
+		},
+		{
+			ID:      `acme`,
+			Effect:  EffectAllow,
+			Actions: []string{`company_delete`},
+			Conditions: []Condition{
+				{Attr: AttrSubjectTenantID, Op: OpIn, Values: []string{`acme`}},
+			},
+		},
+		{
+			ID:      `region-deny`,
+			Effect:  EffectDeny,
+			Actions: []string{`company_update`},
+			Conditions: []Condition{
+				{Attr: AttrRequestCountry, Op: OpIn, Values: []string{`US`}},
+			},
+			FailOpen: true,
+		},
+		{
+			ID:      `region-allow`,
+			Effect:  EffectAllow,
+			Actions: []string{`company_update`},
+			Conditions: []Condition{
+				{Attr: AttrSubjectTenantID, Op: OpIn, Values: []string{`acme`}},
+				{Attr: AttrRequestCountry, Op: OpIn, Values: []string{`CY`}},
+			},
+			FailOpen: true,
+		},
+	}}
+	day := time.Date(2022, 1, 3, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2022, 1, 3, 3, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		in   Input
+		want Decision
+	}{
+		{
+			name: `Allowed by country`,
+			in:   Input{Action: `company_create`, Request: Request{IP: `1.1.1.1`, Time: day}},
+			want: Decision{Allowed: true, Reasons: []string{`cy`}},
+		},
+		{
+			name: `No matching allow rule`,
+			in:   Input{Action: `company_create`, Request: Request{IP: `2.2.2.2`, Time: day}},
+			want: Decision{Allowed: false, Reasons: []string{`no matching allow rule`}},
+		},
+		{
+			name: `Denied at night`,
+			in:   Input{Action: `company_create`, Request: Request{IP: `1.1.1.1`, Time: night}},
+			want: Decision{Allowed: false, Reasons: []string{`night`}},
+		},
+		{
+			name: `Admin is not limited at night`,
+			in: Input{
+				Subject: Subject{Roles: []string{`admin`}},
+				Action:  `company_create`,
+				Request: Request{IP: `1.1.1.1`, Time: night},
+			},
+			want: Decision{Allowed: true, Reasons: []string{`cy`}},
+		},
+		{
+			name: `Deny wins over allow`,
+			in: Input{
+				Subject: Subject{TenantID: `acme`},
+				Action:  `company_delete`,
+				Request: Request{IP: `2.2.2.2`, Time: day},
+			},
+			want: Decision{Allowed: false, Reasons: []string{`blocked-country`}},
+		},
+		{
+			name: `Deny rule failed to evaluate`,
+			in: Input{
+				Subject: Subject{TenantID: `acme`},
+				Action:  `company_delete`,
+				Request: Request{IP: `3.3.3.3`, Time: day},
+			},
+			want: Decision{
+				Allowed: false,
+				Reasons: []string{`blocked-country: unknown ip`},
+				Err:     errors.New(`unknown ip`),
+			},
+		},
+		{
+			name: `Allow rule failed to evaluate`,
+			in:   Input{Action: `company_create`, Request: Request{IP: `3.3.3.3`, Time: day}},
+			want: Decision{
+				Allowed: false,
+				Reasons: []string{`cy: unknown ip`, `no matching allow rule`},
+				Err:     errors.New(`unknown ip`),
+			},
+		},
+		{
+			name: `Deny rule fails open`,
+			in: Input{
+				Subject: Subject{TenantID: `acme`},
+				Action:  `company_update`,
+				Request: Request{IP: `3.3.3.3`, Time: day},
+			},
+			want: Decision{Allowed: true, Reasons: []string{`region-allow: unknown ip, failed open`}},
+		},
+		{
+			name: `Other conditions of rule failing open apply`,
+			in: Input{
+				Subject: Subject{TenantID: `other`},
+				Action:  `company_update`,
+				Request: Request{IP: `3.3.3.3`, Time: day},
+			},
+			want: Decision{Allowed: false, Reasons: []string{`no matching allow rule`}},
+		},
+		{
+			name: `Rules which evaluated deny though failing open`,
+			in: Input{
+				Subject: Subject{TenantID: `acme`},
+				Action:  `company_update`,
+				Request: Request{IP: `3.3.3.3`, Time: night},
+			},
+			want: Decision{Allowed: false, Reasons: []string{`night`}},
+		},
+	}
+	e := NewEngine(p, testLocator{`1.1.1.1`: `CY`, `2.2.2.2`: `US`})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, e.Evaluate(context.Background(), tt.in))
+		})
+	}
+}
+
+func TestEngine_EvaluateAudit(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := logger.ToContext(context.Background(), zap.New(core))
+	e := NewEngine(&Policy{}, nil)
+
+	d := e.Evaluate(ctx, Input{
+		Subject:  Subject{ID: 1, TenantID: `acme`},
+		Action:   `company_create`,
+		Resource: Resource{Type: `company`},
+	})
+
+	assert.False(t, d.Allowed)
+	entries := logs.FilterMessage("Authorization decision").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, `audit`, fields["log_type"])
+		assert.Equal(t, int64(1), fields["subject_id"])
+		assert.Equal(t, `company_create`, fields["action"])
+		assert.Equal(t, false, fields["allowed"])
+	}
+}
+
+func TestEngine_SetPolicy(t *testing.T) {
+	e := NewEngine(&Policy{}, nil)
+	in := Input{Action: `company_create`}
+	assert.False(t, e.Evaluate(context.Background(), in).Allowed)
+
+	e.SetPolicy(&Policy{Rules: []Rule{{ID: `all`, Effect: EffectAllow, Actions: []string{Any}}}})
+	assert.True(t, e.Evaluate(context.Background(), in).Allowed)
+}
@@ -0,0 +1,151 @@
+// Package policy evaluates authorization rules against the subject, action, resource and request of a decision.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+type Effect string
+
+const (
+	EffectAllow Effect = `allow`
+	EffectDeny  Effect = `deny`
+)
+
+type Operator string
+
+const (
+	// OpIn any value of the attribute is one of the values
+	OpIn Operator = `in`
+	// OpNotIn no value of the attribute is one of the values
+	OpNotIn Operator = `not_in`
+	// OpBetween numeric attribute is within [values[0], values[1]]
+	OpBetween Operator = `between`
+)
+
+// Any matches every action in rule actions and every value in condition values
+const Any = `*`
+
+const (
+	AttrSubjectID       = `subject.id`
+	AttrSubjectTenantID = `subject.tenant_id`
+	AttrSubjectRoles    = `subject.roles`
+	AttrSubjectScopes   = `subject.scopes`
+	AttrAction          = `action`
+	AttrResourceType    = `resource.type`
+	AttrResourceID      = `resource.id`
+	AttrResourceOwnerID = `resource.owner_id`
+	AttrResourceTenant  = `resource.tenant_id`
+	AttrRequestIP       = `request.ip`
+	AttrRequestCountry  = `request.country`
+	// AttrRequestHour hour of the request in UTC, 0-23
+	AttrRequestHour = `request.hour`
+	// AttrRequestWeekday day of the request in UTC, 0 is Sunday
+	AttrRequestWeekday = `request.weekday`
+)
+
+var knownAttrs = map[string]bool{
+	AttrSubjectID:       true,
+	AttrSubjectTenantID: true,
+	AttrSubjectRoles:    true,
+	AttrSubjectScopes:   true,
+	AttrAction:          true,
+	AttrResourceType:    true,
+	AttrResourceID:      true,
+	AttrResourceOwnerID: true,
+	AttrResourceTenant:  true,
+	AttrRequestIP:       true,
+	AttrRequestCountry:  true,
+	AttrRequestHour:     true,
+	AttrRequestWeekday:  true,
+}
+
+// Condition compares attribute of the input with the values
+type Condition struct {
+	Attr   string   `json:"attr"`
+	Op     Operator `json:"op"`
+	Values []string `json:"values"`
+}
+
+// Rule applies its effect to the actions when all conditions match
+type Rule struct {
+	ID         string      `json:"id"`
+	Effect     Effect      `json:"effect"`
+	Actions    []string    `json:"actions"`
+	Conditions []Condition `json:"conditions"`
+	// FailOpen the country condition is considered matching by allow rule and not matching by deny rule
+	// when the country can't be resolved. Only rules of region policy fail open, policy files can't set it
+	FailOpen bool `json:"-"`
+}
+
+// Policy set of rules. Any matching deny rule denies the action,
+// otherwise it is allowed if at least one allow rule matches.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Parse parses JSON policy and validates its rules
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rule %d: id is required", i)
+		}
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %s: unknown effect %q", rule.ID, rule.Effect)
+		}
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("rule %s: actions are required", rule.ID)
+		}
+		for _, c := range rule.Conditions {
+			if err := c.validate(); err != nil {
+				return fmt.Errorf("rule %s: %w", rule.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Condition) validate() error {
+	if !knownAttrs[c.Attr] {
+		return fmt.Errorf("unknown attribute %q", c.Attr)
+	}
+	switch c.Op {
+	case OpIn, OpNotIn:
+	case OpBetween:
+		if len(c.Values) != 2 {
+			return fmt.Errorf("%s of %s requires two values", c.Op, c.Attr)
+		}
+		for _, v := range c.Values {
+			if _, err := strconv.Atoi(v); err != nil {
+				return fmt.Errorf("%s of %s requires numbers: %w", c.Op, c.Attr, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+	return nil
+}
+
+// Merge policy with rules of other policies
+func Merge(policies ...*Policy) *Policy {
+	res := &Policy{}
+	for _, p := range policies {
+		if p != nil {
+			res.Rules = append(res.Rules, p.Rules...)
+		}
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: `Valid policy`,
+			data: `{"rules": [
+				{"id": "office-hours", "effect": "deny", "actions": ["*"],
+					"conditions": [{"attr": "request.hour", "op": "between", "values": ["0", "6"]}]},
+				{"id": "acme", "effect": "allow", "actions": ["company_create"],
+					"conditions": [{"attr": "subject.tenant_id", "op": "in", "values": ["acme"]}]}
+			]}`,
+		},
+		{
+			name:    `Rule without id`,
+			data:    `{"rules": [{"effect": "allow", "actions": ["*"]}]}`,
+			wantErr: `rule 0: id is required`,
+		},
+		{
+			name:    `Unknown effect`,
+			data:    `{"rules": [{"id": "r", "effect": "maybe", "actions": ["*"]}]}`,
+			wantErr: `rule r: unknown effect "maybe"`,
+		},
+		{
+			name:    `Rule without actions`,
+			data:    `{"rules": [{"id": "r", "effect": "allow"}]}`,
+			wantErr: `rule r: actions are required`,
+		},
+		{
+			name:    `Unknown attribute`,
+			data:    `{"rules": [{"id": "r", "effect": "allow", "actions": ["*"], "conditions": [{"attr": "subject.name", "op": "in"}]}]}`,
+			wantErr: `rule r: unknown attribute "subject.name"`,
+		},
+		{
+			name:    `Unknown operator`,
+			data:    `{"rules": [{"id": "r", "effect": "allow", "actions": ["*"], "conditions": [{"attr": "action", "op": "like"}]}]}`,
+			wantErr: `rule r: unknown operator "like"`,
+		},
+		{
+			name:    `Between requires two numbers`,
+			data:    `{"rules": [{"id": "r", "effect": "allow", "actions": ["*"], "conditions": [{"attr": "request.hour", "op": "between", "values": ["9"]}]}]}`,
+			wantErr: `rule r: between of request.hour requires two values`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Package ratelimit limits requests of clients by token buckets kept in memory or in Redis
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+// Limit token bucket refilled by Rate tokens every Per, holding up to Burst tokens
+type Limit struct {
+	Rate int `json:"rate"`
+	// Per period of Rate, e.g. 1s, 1m
+	Per string `json:"per"`
+	// Burst requests allowed at once, Rate by default
+	Burst int `json:"burst"`
+
+	per time.Duration
+}
+
+func (l *Limit) parse() error {
+	per, err := time.ParseDuration(l.Per)
+	if err != nil {
+		return fmt.Errorf("invalid period: %w", err)
+	}
+	if l.Rate <= 0 || per <= 0 || l.Burst < 0 {
+		return fmt.Errorf("rate, period and burst must be positive")
+	}
+	l.per = per
+	if l.Burst == 0 {
+		l.Burst = l.Rate
+	}
+	return nil
+}
+
+// perSecond tokens added to the bucket every second
+func (l Limit) perSecond() float64 {
+	return float64(l.Rate) / l.per.Seconds()
+}
+
+// Rules limits of requests. The limit of a principal replaces the limit of a route, routes without limit
+// share the default limit. Requests are not limited if no limit applies.
+type Rules struct {
+	Default *Limit `json:"default"`
+	// Routes limits by method and path template, e.g. "POST /api/companies"
+	Routes map[string]Limit `json:"routes"`
+	// Principals limits by principal, e.g. "user:42", "api_key:7", "client:sa_0123", "ip:203.0.113.7"
+	Principals map[string]Limit `json:"principals"`
+}
+
+// DefaultRules limits used when none are configured
+func DefaultRules() Rules {
+	return Rules{
+		Default: &Limit{Rate: 50, per: time.Second, Per: "1s", Burst: 100},
+		Routes: map[string]Limit{
+			"POST /api/companies":        {Rate: 30, per: time.Minute, Per: "1m", Burst: 10},
+			"POST /api/companies/import": {Rate: 10, per: time.Hour, Per: "1h", Burst: 2},
+			"POST /auth/token":           {Rate: 10, per: time.Minute, Per: "1m", Burst: 10},
+		},
+	}
+}
+
+// ParseRules parses JSON rules and validates their limits
+func ParseRules(data []byte) (Rules, error) {
+	var r Rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Rules{}, fmt.Errorf("failed to parse rate limits: %w", err)
+	}
+	if r.Default != nil {
+		if err := r.Default.parse(); err != nil {
+			return Rules{}, fmt.Errorf("invalid default rate limit: %w", err)
+		}
+	}
+	for _, limits := range []map[string]Limit{r.Routes, r.Principals} {
+		for name, limit := range limits {
+			if err := limit.parse(); err != nil {
+				return Rules{}, fmt.Errorf("invalid rate limit of %s: %w", name, err)
+			}
+			limits[name] = limit
+		}
+	}
+	return r, nil
+}
+
+// LoadRules loads rules from the file or from the env, default rules are used if none are configured
+func LoadRules(conf *config.RateLimit) (Rules, error) {
+	switch {
+	case conf.File != "":
+		data, err := os.ReadFile(conf.File)
+		if err != nil {
+			return Rules{}, fmt.Errorf("failed to read rate limits file: %w", err)
+		}
+		return ParseRules(data)
+	case conf.Rules != "":
+		return ParseRules([]byte(conf.Rules))
+	}
+	return DefaultRules(), nil
+}
+
+// Result of taking a token from the bucket
+type Result struct {
+	Allowed bool
+	// Limit size of the bucket
+	Limit     int
+	Remaining int
+	// RetryAfter time until the next token if the request is not allowed
+	RetryAfter time.Duration
+	// Reset time until the bucket is full
+	Reset time.Duration
+}
+
+// Store keeps token buckets by key
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Limiter takes tokens of the route and principal from buckets of the store
+type Limiter struct {
+	rules Rules
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(rules Rules, store Store) *Limiter {
+	return &Limiter{
+		rules: rules,
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow takes a token of the principal for the route, ok is false if no limit applies
+func (l *Limiter) Allow(ctx context.Context, route, principal string) (res Result, ok bool, err error) {
+	limit, scope, ok := l.limit(route, principal)
+	if !ok {
+		return Result{}, false, nil
+	}
+	res, err = l.store.Take(ctx, scope+"|"+principal, limit, l.now())
+	if err != nil {
+		return Result{}, true, fmt.Errorf("rate limit store: %w", err)
+	}
+	return res, true, nil
+}
+
+// limit of the request and scope of its bucket, routes without own limit share the default bucket
+func (l *Limiter) limit(route, principal string) (Limit, string, bool) {
+	routeLimit, hasRoute := l.rules.Routes[route]
+	scope := "default"
+	if hasRoute {
+		scope = route
+	}
+	if limit, ok := l.rules.Principals[principal]; ok {
+		return limit, scope, true
+	}
+	if hasRoute {
+		return routeLimit, scope, true
+	}
+	if l.rules.Default != nil {
+		return *l.rules.Default, scope, true
+	}
+	return Limit{}, "", false
+}
+
+// result of the bucket holding tokens after the request
+func (l Limit) result(allowed bool, tokens float64) Result {
+	rate := l.perSecond()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(l.Burst) - tokens) / rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return res
+}
+
+// refill tokens of the bucket last updated at updated
+func (l Limit) refill(tokens float64, updated, now time.Time) float64 {
+	if elapsed := now.Sub(updated).Seconds(); elapsed > 0 {
+		tokens += elapsed * l.perSecond()
+	}
+	return math.Min(tokens, float64(l.Burst))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
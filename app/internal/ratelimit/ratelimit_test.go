@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+func TestParseRules(t *testing.T) {
+	r, err := ParseRules([]byte(`{
+		"default": {"rate": 10, "per": "1s"},
+		"routes": {"POST /api/companies": {"rate": 30, "per": "1m", "burst": 5}},
+		"principals": {"api_key:7": {"rate": 1, "per": "1h"}}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 10, Per: "1s", Burst: 10, per: time.Second}, *r.Default)
+	assert.Equal(t, Limit{Rate: 30, Per: "1m", Burst: 5, per: time.Minute}, r.Routes[`POST /api/companies`])
+	assert.Equal(t, Limit{Rate: 1, Per: "1h", Burst: 1, per: time.Hour}, r.Principals[`api_key:7`])
+
+	_, err = ParseRules([]byte(`{"default": {"rate": 10, "per": "second"}}`))
+	assert.Error(t, err)
+	_, err = ParseRules([]byte(`{"routes": {"GET /api/companies": {"rate": 0, "per": "1s"}}}`))
+	assert.EqualError(t, err, `invalid rate limit of GET /api/companies: rate, period and burst must be positive`)
+	_, err = ParseRules([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestLoadRules(t *testing.T) {
+	r, err := LoadRules(&config.RateLimit{})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRules(), r)
+
+	r, err = LoadRules(&config.RateLimit{Rules: `{}`})
+	assert.NoError(t, err)
+	assert.Nil(t, r.Default)
+
+	_, err = LoadRules(&config.RateLimit{File: filepath.Join(t.TempDir(), `missing.json`)})
+	assert.Error(t, err)
+}
+
+func TestLimiter_limit(t *testing.T) {
+	r, err := ParseRules([]byte(`{
+		"default": {"rate": 10, "per": "1s"},
+		"routes": {"POST /api/companies": {"rate": 30, "per": "1m"}},
+		"principals": {"api_key:7": {"rate": 1, "per": "1h"}}
+	}`))
+	require.NoError(t, err)
+	l := NewLimiter(r, NewMemoryStore())
+
+	tests := []struct {
+		name      string
+		route     string
+		principal string
+		wantRate  int
+		wantScope string
+	}{
+		{name: `Route limit`, route: `POST /api/companies`, principal: `user:1`, wantRate: 30, wantScope: `POST /api/companies`},
+		{name: `Default limit`, route: `GET /api/companies`, principal: `user:1`, wantRate: 10, wantScope: `default`},
+		{name: `Principal limit on route`, route: `POST /api/companies`, principal: `api_key:7`, wantRate: 1, wantScope: `POST /api/companies`},
+		{name: `Principal limit`, route: `GET /api/companies`, principal: `api_key:7`, wantRate: 1, wantScope: `default`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, scope, ok := l.limit(tt.route, tt.principal)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantRate, limit.Rate)
+			assert.Equal(t, tt.wantScope, scope)
+		})
+	}
+
+	_, _, ok := NewLimiter(Rules{}, NewMemoryStore()).limit(`GET /api/companies`, `user:1`)
+	assert.False(t, ok)
+}
+
+func TestStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]Store{
+		`memory`: NewMemoryStore(),
+		`redis`:  NewRedisStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), `test:`),
+	}
+	limit := Limit{Rate: 2, Per: "1s", Burst: 3, per: time.Second}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			for i := 2; i >= 0; i-- {
+				res, err := store.Take(ctx, `default|user:1`, limit, now)
+				require.NoError(t, err)
+				assert.True(t, res.Allowed)
+				assert.Equal(t, 3, res.Limit)
+				assert.Equal(t, i, res.Remaining)
+			}
+			res, err := store.Take(ctx, `default|user:1`, limit, now)
+			require.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+			assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+			// other principals have own buckets
+			res, err = store.Take(ctx, `default|user:2`, limit, now)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+
+			// bucket is refilled by rate
+			now = now.Add(500 * time.Millisecond)
+			res, err = store.Take(ctx, `default|user:1`, limit, now)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 0, res.Remaining)
+
+			// and doesn't grow over burst
+			now = now.Add(time.Hour)
+			res, err = store.Take(ctx, `default|user:1`, limit, now)
+			require.NoError(t, err)
+			assert.Equal(t, 2, res.Remaining)
+		})
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore()
+	limit := Limit{Rate: 1, Per: "1s", Burst: 1, per: time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _ = s.Take(context.Background(), `default|user:1`, limit, now)
+	_, _ = s.Take(context.Background(), `default|user:2`, limit, now.Add(sweepInterval))
+	assert.Len(t, s.buckets, 1, `full bucket of user 1 is removed`)
+}
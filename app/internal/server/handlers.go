@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"expvar"
 	"net/http"
 	"net/http/pprof"
 	"time"
@@ -16,8 +17,9 @@ func (s *Server) initApp() *http.Server {
 	http.Handle("/", r)
 
 	return &http.Server{
-		Addr:    s.Config.Port,
-		Handler: r,
+		Addr:      s.Config.Port,
+		Handler:   r,
+		TLSConfig: s.tlsConfig,
 	}
 }
 
@@ -27,6 +29,7 @@ func (s *Server) CreateDefaultRouter() *mux.Router {
 	r.HandleFunc("/debug/pprof/", pprof.Index)
 	r.HandleFunc("/debug/pprof/{action}", pprof.Index)
 	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.Handle("/debug/vars", expvar.Handler())
 	s.Router = r
 	return s.Router
 }
@@ -37,14 +40,23 @@ func (s *Server) createHTTPHandler() http.Handler {
 		"X-Requested-With",
 		"Authorization",
 		"authorization",
+		"X-API-Key",
+		"Idempotency-Key",
 		"sentry-trace",
 		"content-type",
 		"Content-Type",
 	})
 	methodsOk := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE"})
 	originsOk := handlers.AllowedOrigins([]string{"*"})
+	exposedOk := handlers.ExposedHeaders([]string{
+		"RateLimit-Limit",
+		"RateLimit-Remaining",
+		"RateLimit-Reset",
+		"Retry-After",
+		"Idempotent-Replayed",
+	})
 
-	return handlers.CORS(originsOk, headersOk, methodsOk)(s.Router)
+	return handlers.CORS(originsOk, headersOk, methodsOk, exposedOk)(s.Router)
 }
 
 func (s *Server) createHealthChecker() func(rw http.ResponseWriter, r *http.Request) {
@@ -77,6 +89,17 @@ func (s *Server) createHealthChecker() func(rw http.ResponseWriter, r *http.Requ
 			}
 		}
 
+		for host, err := range s.Storage.ReplicasStatus() {
+			name := "DB replica " + host
+			if err != nil {
+				ErrorsList[name] = err.Error()
+			} else {
+				ServicesList[name] = ServiceStatus{
+					Status: `OK`,
+				}
+			}
+		}
+
 		res, _ := json.Marshal(HealthCheckResponse{
 			Timestamp: time.Now().Format(time.RFC3339),
 			Services:  ServicesList,
@@ -2,19 +2,30 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 
 	ipclient "github.com/M-Fisher/companies_api/app/clients/ipdata"
 	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/clientip"
+	"github.com/M-Fisher/companies_api/app/internal/ratelimit"
+	"github.com/M-Fisher/companies_api/app/internal/services/accounts"
 	"github.com/M-Fisher/companies_api/app/internal/services/auth"
 	"github.com/M-Fisher/companies_api/app/internal/services/companies"
 	"github.com/M-Fisher/companies_api/app/internal/services/events"
 	"github.com/M-Fisher/companies_api/app/internal/services/events/clients/kafka"
+	"github.com/M-Fisher/companies_api/app/internal/services/idempotency"
+	"github.com/M-Fisher/companies_api/app/internal/services/quotas"
+	"github.com/M-Fisher/companies_api/app/internal/services/revocation"
 	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
 )
 
@@ -23,9 +34,25 @@ type Server struct {
 	CompaniesService companies.CompaniesService
 	EventsService    events.EventsService
 	AuthService      auth.AuthService
+	AccountsService  accounts.AccountsService
+	Revocations      revocation.RevocationService
+	Quotas           quotas.QuotasService
 	Storage          *postgres.DB
 	Log              *zap.Logger
 	Config           *config.Config
+	// JWTKeys verify signatures of JWT
+	JWTKeys auth.KeyProvider
+	// ClientIP resolves address of the client behind trusted proxies
+	ClientIP *clientip.Resolver
+	// RateLimiter limits requests of clients, requests are not limited if nil
+	RateLimiter *ratelimit.Limiter
+	// Idempotency stores responses of POST requests with Idempotency-Key, the header is ignored if nil
+	Idempotency idempotency.IdempotencyService
+	// CertPrincipals users authenticated by client certificates
+	CertPrincipals auth.CertPrincipals
+	tlsConfig      *tls.Config
+	// stopWatchers stop background reloading of policies and keys
+	stopWatchers []func()
 }
 
 func NewServer(cfg *config.Config) *Server {
@@ -44,21 +71,160 @@ func NewServer(cfg *config.Config) *Server {
 	}
 
 	evService := events.NewService(&cfg.Kafka, kafkaClient, srv.Log)
-	authService := auth.NewService(ipclient.NewClient(cfg.IPApiRequestTimeout))
+	ipDataProvider, err := srv.newIPDataProvider()
+	if err != nil {
+		log.Fatal("Failed to create IP data provider", zap.Error(err))
+	}
+	authService := auth.NewService(ipDataProvider)
+	regionPolicy, err := auth.LoadRegionPolicy(&cfg.RegionPolicy)
+	if err != nil {
+		log.Fatal("Failed to load region policy", zap.Error(err))
+	}
+	authService.SetRegionPolicy(regionPolicy)
+	accessPolicy, err := auth.LoadAccessPolicy(&cfg.AccessPolicy)
+	if err != nil {
+		log.Fatal("Failed to load access policy", zap.Error(err))
+	}
+	authService.SetAccessPolicy(accessPolicy)
+	ipPolicy, err := auth.LoadIPPolicy(&cfg.IPPolicy)
+	if err != nil {
+		log.Fatal("Failed to load IP policy", zap.Error(err))
+	}
+	authService.SetIPPolicy(ipPolicy)
+	srv.stopWatchers = append(srv.stopWatchers,
+		authService.WatchRegionPolicy(&cfg.RegionPolicy, srv.Log),
+		authService.WatchAccessPolicy(&cfg.AccessPolicy, srv.Log),
+		authService.WatchIPPolicy(&cfg.IPPolicy, srv.Log),
+	)
+
+	if !cfg.JWTRequireExpiry {
+		srv.Log.Warn("Tokens without exp claim are accepted, set JWT_REQUIRE_EXPIRY=true once issuers set it")
+	}
+	jwtKeys := &auth.JWTKeys{Secret: cfg.JWTSecret}
+	if cfg.JWKSURL != "" {
+		jwtKeys.JWKS, err = auth.NewJWKS(cfg.JWKSURL, cfg.JWKSRequestTimeout, srv.Log)
+		if err != nil {
+			log.Fatal("Failed to load JWKS", zap.Error(err))
+		}
+		srv.stopWatchers = append(srv.stopWatchers, jwtKeys.JWKS.Watch(cfg.JWKSRefreshInterval))
+	}
+	srv.JWTKeys = jwtKeys
+
+	srv.ClientIP, err = clientip.NewResolver(cfg.TrustedProxies, cfg.ClientIPHeader)
+	if err != nil {
+		log.Fatal("Failed to configure trusted proxies", zap.Error(err))
+	}
+
+	srv.RateLimiter, err = newRateLimiter(&cfg.RateLimit)
+	if err != nil {
+		log.Fatal("Failed to configure rate limits", zap.Error(err))
+	}
+
+	srv.tlsConfig, err = newTLSConfig(&cfg.TLS)
+	if err != nil {
+		log.Fatal("Failed to configure TLS", zap.Error(err))
+	}
+	srv.CertPrincipals, err = auth.LoadCertPrincipals(cfg.TLS.ClientPrincipalsFile)
+	if err != nil {
+		log.Fatal("Failed to load client principals", zap.Error(err))
+	}
+
+	revocations := revocation.NewService(dbService, srv.Log)
+	if err := revocations.Refresh(context.Background()); err != nil {
+		log.Fatal("Failed to load token revocations", zap.Error(err))
+	}
+	srv.stopWatchers = append(srv.stopWatchers, revocations.Watch(cfg.TokenRevocationRefreshInterval))
+	srv.Revocations = revocations
+
+	quotaPlans, err := quotas.LoadPlans(&cfg.Quota)
+	if err != nil {
+		log.Fatal("Failed to load quota plans", zap.Error(err))
+	}
+	quotasService := quotas.NewService(dbService, quotaPlans, srv.Log)
+	srv.stopWatchers = append(srv.stopWatchers, quotasService.Watch(cfg.Quota.CleanupInterval))
+	srv.Quotas = quotasService
+
+	idempotencyService := idempotency.NewService(dbService, cfg.Idempotency.TTL, cfg.Idempotency.LockTimeout, srv.Log)
+	srv.stopWatchers = append(srv.stopWatchers, idempotencyService.Watch(cfg.Idempotency.CleanupInterval))
+	srv.Idempotency = idempotencyService
 
 	srv.Storage = dbService
 	srv.AuthService = authService
-	srv.CompaniesService = companies.NewService(dbService, authService, evService, srv.Log)
+	srv.CompaniesService = companies.NewService(dbService, authService, evService, quotasService, srv.Log)
 	srv.EventsService = evService
+	srv.AccountsService = accounts.NewService(dbService, &auth.TokenIssuer{
+		Secret:   cfg.JWTSecret,
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+		TTL:      cfg.ServiceTokenTTL,
+	}, srv.Log)
 
 	return &srv
 }
 
+// newIPDataProvider creates provider of countries of requests selected in config
+func (s *Server) newIPDataProvider() (auth.IPDataProvider, error) {
+	cfg := &s.Config.IPData
+	var ipapi ipclient.Provider = ipclient.NewClient(s.Config.IPApiRequestTimeout)
+	if cfg.BreakerFailures > 0 {
+		ipapi = ipclient.NewBreaker("ipapi", ipapi, cfg.BreakerFailures, cfg.BreakerCooldown)
+	}
+	var chain ipclient.Chain
+	switch cfg.Provider {
+	case "ipapi":
+		chain = ipclient.Chain{{Name: "ipapi", Provider: ipapi}}
+	case "mmdb":
+		mmdb, err := ipclient.NewMMDBClient(cfg.MMDBFile, s.Log)
+		if err != nil && cfg.Fallback && errors.Is(err, fs.ErrNotExist) {
+			// deployments upgraded from ipapi-only lookups may have no database yet
+			s.Log.Warn("MMDB file is missing, countries are looked up only by ipapi.co", zap.String("file", cfg.MMDBFile))
+			chain = ipclient.Chain{{Name: "ipapi", Provider: ipapi}}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.stopWatchers = append(s.stopWatchers, mmdb.Watch(cfg.ReloadInterval))
+		chain = ipclient.Chain{{Name: "mmdb", Provider: mmdb}}
+		if cfg.Fallback {
+			chain = append(chain, ipclient.Named{Name: "ipapi", Provider: ipapi})
+		}
+	default:
+		return nil, fmt.Errorf("unknown IP data provider: %q", cfg.Provider)
+	}
+	if cfg.CacheTTL <= 0 {
+		return chain, nil
+	}
+	return ipclient.NewCache(chain, cfg.CacheTTL, cfg.CacheErrorTTL, cfg.CacheSize), nil
+}
+
+// newRateLimiter creates limiter keeping buckets in Redis if it is configured, otherwise in memory
+func newRateLimiter(conf *config.RateLimit) (*ratelimit.Limiter, error) {
+	rules, err := ratelimit.LoadRules(conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.RedisURL == "" {
+		return ratelimit.NewLimiter(rules, ratelimit.NewMemoryStore()), nil
+	}
+	opts, err := redis.ParseURL(conf.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	return ratelimit.NewLimiter(rules, ratelimit.NewRedisStore(redis.NewClient(opts), "companies_api:ratelimit:")), nil
+}
+
 func (s *Server) Run() {
 	srv := s.initApp()
-	s.Log.Info("Starting server app", zap.String("port", s.Config.Port))
+	s.Log.Info("Starting server app", zap.String("port", s.Config.Port), zap.Bool("tls", s.tlsConfig != nil))
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tlsConfig != nil {
+			err = srv.ListenAndServeTLS(s.Config.TLS.CertFile, s.Config.TLS.KeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.Log.Fatal("http server failed", zap.Error(err))
 		}
 	}()
@@ -66,6 +232,9 @@ func (s *Server) Run() {
 
 func (s *Server) Stop() {
 	s.Log.Info("Stopping server")
+	for _, stop := range s.stopWatchers {
+		stop()
+	}
 	err := s.Storage.Close(context.Background())
 	if err != nil {
 		s.Log.Error("Failed to stop DB service", zap.Error(err))
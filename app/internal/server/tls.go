@@ -0,0 +1,42 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+// newTLSConfig TLS settings of the server, nil if TLS is disabled
+func newTLSConfig(conf *config.TLS) (*tls.Config, error) {
+	if conf.CertFile == "" {
+		if conf.ClientCAFile != "" {
+			return nil, errors.New("client certificates require TLS certificate of the server")
+		}
+		return nil, nil
+	}
+	res := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if conf.ClientCAFile == "" {
+		return res, nil
+	}
+
+	data, err := os.ReadFile(conf.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates found in client CA file")
+	}
+	res.ClientCAs = pool
+	res.ClientAuth = tls.VerifyClientCertIfGiven
+	if conf.RequireClientCert {
+		res.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return res, nil
+}
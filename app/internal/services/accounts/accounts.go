@@ -0,0 +1,57 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+type AccountsService interface {
+	CreateServiceAccount(ctx context.Context, req models.CreateServiceAccountRequest) (*models.ServiceAccountCredentials, error)
+	GetServiceAccounts(ctx context.Context) ([]*models.ServiceAccount, error)
+	RotateSecret(ctx context.Context, id int64) (*models.ServiceAccountCredentials, error)
+	DisableServiceAccount(ctx context.Context, id int64) error
+	IssueToken(ctx context.Context, clientID, clientSecret string, scopes []string) (*models.Token, error)
+	CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) (*models.APIKeyWithSecret, error)
+	GetAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*auth.JWTUser, error)
+}
+
+var (
+	ErrAccountNotFound = errors.New("service account not found")
+	ErrInvalidAccount  = errors.New("invalid service account")
+	// ErrInvalidClient unknown or disabled client or wrong secret
+	ErrInvalidClient = errors.New("invalid client")
+	ErrInvalidScope  = errors.New("invalid scope")
+)
+
+type service struct {
+	db     *postgres.DB
+	issuer *auth.TokenIssuer
+	log    *zap.Logger
+}
+
+func NewService(db *postgres.DB, issuer *auth.TokenIssuer, log *zap.Logger) *service {
+	return &service{
+		db:     db,
+		issuer: issuer,
+		log:    log,
+	}
+}
+
+func toModel(acc *postgres.ServiceAccount) models.ServiceAccount {
+	return models.ServiceAccount{
+		ID:        acc.ID,
+		Name:      acc.Name,
+		ClientID:  acc.ClientID,
+		Scopes:    acc.Scopes,
+		Disabled:  acc.Disabled,
+		CreatedAt: acc.CreatedAt,
+	}
+}
@@ -0,0 +1,171 @@
+package accounts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+const (
+	apiKeyPrefix = `ak_`
+	// apiKeyPrefixLen length of the public part of the key, e.g. ak_0123456789ab
+	apiKeyPrefixLen = len(apiKeyPrefix) + 12
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid api key")
+	ErrInvalidAPIKeyParams = errors.New("invalid api key params")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+)
+
+// newAPIKey generates key of form ak_<prefix>_<secret>, prefix is stored to find the key, the whole key is stored hashed
+func newAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, (apiKeyPrefixLen-len(apiKeyPrefix))/2)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("generate random: %w", err)
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", "", err
+	}
+	prefix = apiKeyPrefix + hex.EncodeToString(b)
+	key = prefix + "_" + secret
+	return key, prefix, hashSecret(key), nil
+}
+
+func apiKeyToModel(k *postgres.APIKey) models.APIKey {
+	return models.APIKey{
+		ID:         k.ID,
+		Prefix:     k.Prefix,
+		Name:       k.Name,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+// CreateAPIKey creates key of the caller, scopes must be checked against scopes of the caller beforehand
+func (s *service) CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) (*models.APIKeyWithSecret, error) {
+	if err := validateCredentials(req.Name, req.Scopes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAPIKeyParams, err)
+	}
+	key, prefix, hash, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	data := postgres.APIKey{
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  req.Scopes,
+	}
+	err = s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		data.ID, err = q.CreateAPIKey(ctx, data)
+		if err != nil {
+			return fmt.Errorf("failed to create api key: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyWithSecret{
+		APIKey: apiKeyToModel(&data),
+		Key:    key,
+	}, nil
+}
+
+// GetAPIKeys returns keys of the caller
+func (s *service) GetAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	caller, ok := identity.FromContext(ctx)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var res []*models.APIKey
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		keys, err := q.GetAPIKeys(ctx, caller.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get api keys: %w", err)
+		}
+		res = make([]*models.APIKey, len(keys))
+		for i, k := range keys {
+			m := apiKeyToModel(k)
+			res[i] = &m
+		}
+		return nil
+	}, postgres.WithReadOnly())
+
+	return res, err
+}
+
+// RevokeAPIKey revokes key of the caller, admins can revoke any key of their tenant
+func (s *service) RevokeAPIKey(ctx context.Context, id int64) error {
+	caller, ok := identity.FromContext(ctx)
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	userID := &caller.UserID
+	if caller.HasRole(auth.RoleAdmin) {
+		userID = nil
+	}
+
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		err := q.RevokeAPIKey(ctx, id, userID)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return ErrAPIKeyNotFound
+			}
+			return fmt.Errorf("failed to revoke api key: %w", err)
+		}
+		return nil
+	})
+}
+
+// AuthenticateAPIKey returns the user the key belongs to, the user is granted only the scopes of the key
+func (s *service) AuthenticateAPIKey(ctx context.Context, key string) (*auth.JWTUser, error) {
+	if len(key) <= apiKeyPrefixLen || !strings.HasPrefix(key, apiKeyPrefix) || key[apiKeyPrefixLen] != '_' {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var res *postgres.APIKey
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		k, err := q.GetAPIKeyByPrefix(ctx, key[:apiKeyPrefixLen])
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return ErrInvalidAPIKey
+			}
+			return fmt.Errorf("failed to get api key: %w", err)
+		}
+		// keys without user were created by service accounts, they would outlive the account
+		if k.RevokedAt != nil || k.UserID == 0 || !checkSecret(key, k.KeyHash) {
+			return ErrInvalidAPIKey
+		}
+		if err := q.TouchAPIKey(ctx, k.ID); err != nil {
+			return fmt.Errorf("failed to update api key usage: %w", err)
+		}
+		res = k
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.JWTUser{
+		ID:       res.UserID,
+		TenantID: res.TenantID,
+		Scopes:   res.Scopes,
+		APIKeyID: res.ID,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+)
+
+var apiKeyCols = []string{`id`, `tenant_id`, `user_id`, `name`, `prefix`, `key_hash`, `scopes`, `created_at`, `last_used_at`, `revoked_at`}
+
+const testAPIKey = `ak_0123456789ab_c2VjcmV0`
+
+func expectAPIKey(pgxMock pgxmock.PgxConnIface, key string, userID int64, revokedAt *time.Time) {
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectQuery("SELECT .+ FROM api_keys WHERE prefix = \\$1").
+		WithArgs(`ak_0123456789ab`).
+		WillReturnRows(pgxMock.NewRows(apiKeyCols).
+			AddRow(int64(2), `acme`, userID, `ci`, `ak_0123456789ab`, hashSecret(key), []string{auth.ScopeCompaniesRead}, time.Now(), nil, revokedAt))
+}
+
+func TestCreateAPIKeyOk(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectQuery("INSERT INTO api_keys").
+		WithArgs(pgxmock.AnyArg(), `ci`, pgxmock.AnyArg(), []string{auth.ScopeCompaniesRead}, `test`, int64(1)).
+		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(int64(2)))
+	pgxMock.ExpectCommit()
+
+	got, err := s.CreateAPIKey(testCtx, models.CreateAPIKeyRequest{Name: `ci`, Scopes: []string{auth.ScopeCompaniesRead}})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.ID)
+	assert.Regexp(t, `^ak_[0-9a-f]{12}$`, got.Prefix)
+	assert.Equal(t, got.Prefix+`_`, got.Key[:apiKeyPrefixLen+1])
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestCreateAPIKeyInvalid(t *testing.T) {
+	pgxMock, s := newTestService(t)
+
+	_, err := s.CreateAPIKey(testCtx, models.CreateAPIKeyRequest{Name: `ci`, Scopes: []string{`companies:all`}})
+
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyParams)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestAuthenticateAPIKeyOk(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	expectAPIKey(pgxMock, testAPIKey, 5, nil)
+	pgxMock.ExpectExec("UPDATE api_keys SET last_used_at = now\\(\\)").
+		WithArgs(int64(2)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	pgxMock.ExpectCommit()
+
+	got, err := s.AuthenticateAPIKey(context.Background(), testAPIKey)
+
+	require.NoError(t, err)
+	assert.Equal(t, &auth.JWTUser{ID: 5, TenantID: `acme`, Scopes: []string{auth.ScopeCompaniesRead}, APIKeyID: 2}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestAuthenticateAPIKeyRejected(t *testing.T) {
+	revoked := time.Now()
+	tests := []struct {
+		name      string
+		storedKey string
+		userID    int64
+		revokedAt *time.Time
+	}{
+		{name: `Wrong secret`, storedKey: `ak_0123456789ab_b3RoZXI`, userID: 5},
+		{name: `Revoked`, storedKey: testAPIKey, userID: 5, revokedAt: &revoked},
+		{name: `Key of service account`, storedKey: testAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, s := newTestService(t)
+			expectAPIKey(pgxMock, tt.storedKey, tt.userID, tt.revokedAt)
+			pgxMock.ExpectRollback()
+
+			_, err := s.AuthenticateAPIKey(context.Background(), testAPIKey)
+
+			assert.ErrorIs(t, err, ErrInvalidAPIKey)
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthenticateAPIKeyMalformed(t *testing.T) {
+	pgxMock, s := newTestService(t)
+
+	for _, key := range []string{``, `ak_0123`, `ak_0123456789abc2VjcmV0`, `xx_0123456789ab_c2VjcmV0`} {
+		_, err := s.AuthenticateAPIKey(context.Background(), key)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, key)
+	}
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestRevokeAPIKeyByAdmin(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("UPDATE api_keys SET revoked_at = now\\(\\) WHERE \\(id = \\$1 AND revoked_at IS NULL AND tenant_id = \\$2\\)").
+		WithArgs(int64(2), `test`).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	pgxMock.ExpectCommit()
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `test`, Roles: []string{auth.RoleAdmin}})
+	err := s.RevokeAPIKey(ctx, 2)
+
+	assert.NoError(t, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestRevokeAPIKeyOwn(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("UPDATE api_keys SET revoked_at = now\\(\\) WHERE \\(id = \\$1 AND revoked_at IS NULL AND tenant_id = \\$2 AND user_id = \\$3\\)").
+		WithArgs(int64(2), `test`, int64(0)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	pgxMock.ExpectRollback()
+
+	// service accounts have user id 0, they must not revoke keys of other users
+	ctx := identity.ToContext(context.Background(), identity.Identity{TenantID: `test`})
+	err := s.RevokeAPIKey(ctx, 2)
+
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestRevokeAPIKeyNotFound(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("UPDATE api_keys").
+		WithArgs(int64(2), `test`, int64(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	pgxMock.ExpectRollback()
+
+	err := s.RevokeAPIKey(testCtx, 2)
+
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
@@ -0,0 +1,47 @@
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const clientIDPrefix = `sa_`
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate random: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newClientID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate random: %w", err)
+	}
+	return clientIDPrefix + hex.EncodeToString(b), nil
+}
+
+// newSecret generates client secret, only its hash is stored
+func newSecret() (secret, hash string, err error) {
+	secret, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, hashSecret(secret), nil
+}
+
+// hashSecret secrets are random 256 bit values, so a fast hash is enough to store them
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func checkSecret(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(hash)) == 1
+}
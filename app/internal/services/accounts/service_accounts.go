@@ -0,0 +1,183 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+// validateCredentials checks name and scopes of service account or api key
+func validateCredentials(name string, scopes []string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name is required")
+	}
+	if len(scopes) == 0 {
+		return errors.New("scopes are required")
+	}
+	for _, scope := range scopes {
+		if !auth.IsKnownScope(scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func (s *service) CreateServiceAccount(
+	ctx context.Context,
+	req models.CreateServiceAccountRequest,
+) (*models.ServiceAccountCredentials, error) {
+	if err := validateCredentials(req.Name, req.Scopes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAccount, err)
+	}
+	clientID, err := newClientID()
+	if err != nil {
+		return nil, err
+	}
+	secret, hash, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	data := postgres.ServiceAccount{
+		Name:       req.Name,
+		ClientID:   clientID,
+		SecretHash: hash,
+		Scopes:     req.Scopes,
+	}
+	err = s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		data.ID, err = q.CreateServiceAccount(ctx, data)
+		if err != nil {
+			return fmt.Errorf("failed to create service account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ServiceAccountCredentials{
+		ServiceAccount: toModel(&data),
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (s *service) GetServiceAccounts(ctx context.Context) ([]*models.ServiceAccount, error) {
+	var res []*models.ServiceAccount
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		accounts, err := q.GetServiceAccounts(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get service accounts: %w", err)
+		}
+		res = make([]*models.ServiceAccount, len(accounts))
+		for i, acc := range accounts {
+			m := toModel(acc)
+			res[i] = &m
+		}
+		return nil
+	}, postgres.WithReadOnly())
+
+	return res, err
+}
+
+// RotateSecret generates new secret of the account, tokens issued with the old one stay valid until they expire
+func (s *service) RotateSecret(ctx context.Context, id int64) (*models.ServiceAccountCredentials, error) {
+	secret, hash, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var res *models.ServiceAccountCredentials
+	err = s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		acc, err := q.UpdateServiceAccountSecret(ctx, id, hash)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return ErrAccountNotFound
+			}
+			return fmt.Errorf("failed to rotate secret: %w", err)
+		}
+		res = &models.ServiceAccountCredentials{
+			ServiceAccount: toModel(acc),
+			ClientSecret:   secret,
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+func (s *service) DisableServiceAccount(ctx context.Context, id int64) error {
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		err := q.DisableServiceAccount(ctx, id)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return ErrAccountNotFound
+			}
+			return fmt.Errorf("failed to disable service account: %w", err)
+		}
+		return nil
+	})
+}
+
+// IssueToken authenticates the client and issues token with requested scopes, all scopes of the account if none requested
+func (s *service) IssueToken(ctx context.Context, clientID, clientSecret string, scopes []string) (*models.Token, error) {
+	var acc *postgres.ServiceAccount
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		var err error
+		acc, err = q.GetServiceAccountByClientID(ctx, clientID)
+		if err != nil {
+			if errors.Is(err, postgres.ErrNotFound) {
+				return ErrInvalidClient
+			}
+			return fmt.Errorf("failed to get service account: %w", err)
+		}
+		return nil
+	}, postgres.WithReadOnly())
+	if err != nil {
+		return nil, err
+	}
+	if acc.Disabled || !checkSecret(clientSecret, acc.SecretHash) {
+		return nil, ErrInvalidClient
+	}
+
+	if len(scopes) == 0 {
+		scopes = acc.Scopes
+	}
+	for _, scope := range scopes {
+		if !contains(acc.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %q is not granted to the client", ErrInvalidScope, scope)
+		}
+	}
+
+	scope := strings.Join(scopes, " ")
+	token, err := s.issuer.Issue(auth.JWTClaims{
+		// service accounts are not users, they don't own companies
+		ID:       "0",
+		TenantID: acc.TenantID,
+		Scope:    scope,
+		ClientID: acc.ClientID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue token: %w", err)
+	}
+
+	return &models.Token{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.issuer.TTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
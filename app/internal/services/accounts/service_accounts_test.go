@@ -0,0 +1,163 @@
+package accounts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+var (
+	testCtx     = identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `test`})
+	accountCols = []string{`id`, `tenant_id`, `name`, `client_id`, `secret_hash`, `scopes`, `disabled`, `created_at`}
+)
+
+func newTestService(t *testing.T) (pgxmock.PgxConnIface, *service) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+	return pgxMock, NewService(dbMock, &auth.TokenIssuer{Secret: `test`, TTL: time.Minute}, zap.NewExample())
+}
+
+func expectAccount(pgxMock pgxmock.PgxConnIface, secret string, disabled bool) {
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadOnly})
+	pgxMock.ExpectQuery("SELECT .+ FROM service_accounts WHERE client_id = \\$1").
+		WithArgs(`sa_1`).
+		WillReturnRows(pgxMock.NewRows(accountCols).
+			AddRow(int64(3), `acme`, `importer`, `sa_1`, hashSecret(secret), []string{auth.ScopeCompaniesRead, auth.ScopeCompaniesWrite}, disabled, time.Now()))
+	pgxMock.ExpectCommit()
+}
+
+func TestCreateServiceAccountOk(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectQuery("INSERT INTO service_accounts").
+		WithArgs(pgxmock.AnyArg(), `importer`, []string{auth.ScopeCompaniesWrite}, pgxmock.AnyArg(), `test`).
+		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(int64(3)))
+	pgxMock.ExpectCommit()
+
+	got, err := s.CreateServiceAccount(testCtx, models.CreateServiceAccountRequest{
+		Name:   `importer`,
+		Scopes: []string{auth.ScopeCompaniesWrite},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), got.ID)
+	assert.Regexp(t, `^sa_[0-9a-f]{24}$`, got.ClientID)
+	assert.NotEmpty(t, got.ClientSecret)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestCreateServiceAccountInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.CreateServiceAccountRequest
+	}{
+		{name: `No name`, req: models.CreateServiceAccountRequest{Scopes: []string{auth.ScopeCompaniesRead}}},
+		{name: `No scopes`, req: models.CreateServiceAccountRequest{Name: `importer`}},
+		{name: `Unknown scope`, req: models.CreateServiceAccountRequest{Name: `importer`, Scopes: []string{`companies:all`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, s := newTestService(t)
+			_, err := s.CreateServiceAccount(testCtx, tt.req)
+			assert.ErrorIs(t, err, ErrInvalidAccount)
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRotateSecretNotFound(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectQuery("UPDATE service_accounts SET secret_hash").
+		WillReturnRows(pgxMock.NewRows(accountCols))
+	pgxMock.ExpectRollback()
+
+	_, err := s.RotateSecret(testCtx, 3)
+
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestIssueTokenOk(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	expectAccount(pgxMock, `secret`, false)
+
+	got, err := s.IssueToken(context.Background(), `sa_1`, `secret`, []string{auth.ScopeCompaniesRead})
+
+	require.NoError(t, err)
+	assert.Equal(t, `Bearer`, got.TokenType)
+	assert.Equal(t, int64(60), got.ExpiresIn)
+	assert.Equal(t, auth.ScopeCompaniesRead, got.Scope)
+
+	user, err := new(auth.JWTUser).ParseWithKeys(context.Background(), got.AccessToken, &auth.JWTKeys{Secret: `test`}, auth.ClaimsValidation{RequireExpiry: true})
+	require.NoError(t, err)
+	assert.Equal(t, `acme`, user.TenantID)
+	assert.Equal(t, `sa_1`, user.ClientID)
+	assert.Equal(t, []string{auth.ScopeCompaniesRead}, user.Scopes)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestIssueTokenAllScopes(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	expectAccount(pgxMock, `secret`, false)
+
+	got, err := s.IssueToken(context.Background(), `sa_1`, `secret`, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, auth.ScopeCompaniesRead+` `+auth.ScopeCompaniesWrite, got.Scope)
+}
+
+func TestIssueTokenRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		disabled bool
+		scopes   []string
+		wantErr  error
+	}{
+		{name: `Wrong secret`, secret: `other`, wantErr: ErrInvalidClient},
+		{name: `Disabled`, secret: `secret`, disabled: true, wantErr: ErrInvalidClient},
+		{name: `Scope not granted`, secret: `secret`, scopes: []string{auth.ScopeCompaniesDelete}, wantErr: ErrInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, s := newTestService(t)
+			expectAccount(pgxMock, `secret`, tt.disabled)
+
+			_, err := s.IssueToken(context.Background(), `sa_1`, tt.secret, tt.scopes)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestIssueTokenUnknownClient(t *testing.T) {
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadOnly})
+	pgxMock.ExpectQuery("SELECT .+ FROM service_accounts").
+		WillReturnRows(pgxMock.NewRows(accountCols))
+	pgxMock.ExpectRollback()
+
+	_, err := s.IssueToken(context.Background(), `sa_1`, `secret`, nil)
+
+	assert.ErrorIs(t, err, ErrInvalidClient)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
+// LoadAccessPolicy loads rules evaluated together with region policy, empty policy is used if no file is configured
+func LoadAccessPolicy(conf *config.AccessPolicy) (*policy.Policy, error) {
+	if conf.File == "" {
+		return &policy.Policy{}, nil
+	}
+	data, err := os.ReadFile(conf.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access policy file: %w", err)
+	}
+	return policy.Parse(data)
+}
+
+// WatchAccessPolicy reloads the access policy file when it is modified. Returned function stops watching.
+func (s *service) WatchAccessPolicy(conf *config.AccessPolicy, log *zap.Logger) func() {
+	return watchFile(conf.File, conf.ReloadInterval, log.With(zap.String("access_policy_file", conf.File)), func() error {
+		p, err := LoadAccessPolicy(conf)
+		if err != nil {
+			return err
+		}
+		s.SetAccessPolicy(p)
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
+func TestLoadAccessPolicy(t *testing.T) {
+	p, err := LoadAccessPolicy(&config.AccessPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, &policy.Policy{}, p)
+
+	file := filepath.Join(t.TempDir(), `policy.json`)
+	err = os.WriteFile(file, []byte(`{"rules": [{"id": "r", "effect": "maybe", "actions": ["*"]}]}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadAccessPolicy(&config.AccessPolicy{File: file})
+	assert.EqualError(t, err, `rule r: unknown effect "maybe"`)
+}
+
+func Test_service_IsActionAllowedAccessPolicy(t *testing.T) {
+	clmock := new(MockIPDataProvider)
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.200.124`).Return(`CY`, nil)
+	s := NewService(clmock)
+	s.SetAccessPolicy(&policy.Policy{Rules: []policy.Rule{
+		{
+			ID:      `frozen-tenant`,
+			Effect:  policy.EffectDeny,
+			Actions: []string{policy.Any},
+			Conditions: []policy.Condition{
+				{Attr: policy.AttrSubjectTenantID, Op: policy.OpIn, Values: []string{`frozen`}},
+			},
+		},
+	}})
+
+	got, err := s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1, TenantID: `acme`}, `192.200.200.124`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1, TenantID: `frozen`}, `192.200.200.124`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.False(t, got)
+}
+
+func Test_service_IsActionAllowedResource(t *testing.T) {
+	clmock := new(MockIPDataProvider)
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.200.124`).Return(`CY`, nil)
+	s := NewService(clmock)
+	s.SetAccessPolicy(&policy.Policy{Rules: []policy.Rule{
+		{
+			ID:      `protected-company`,
+			Effect:  policy.EffectDeny,
+			Actions: []string{ActionCompanyDelete.String()},
+			Conditions: []policy.Condition{
+				{Attr: policy.AttrResourceOwnerID, Op: policy.OpIn, Values: []string{`7`}},
+				{Attr: policy.AttrResourceType, Op: policy.OpIn, Values: []string{ResourceCompany}},
+			},
+		},
+	}})
+	user := &JWTUser{ID: 1, TenantID: `acme`}
+
+	got, err := s.IsActionAllowed(context.Background(), ActionCompanyDelete, user, `192.200.200.124`, policy.Resource{ID: `12`, OwnerID: 7, TenantID: `acme`})
+	assert.NoError(t, err)
+	assert.False(t, got)
+
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyDelete, user, `192.200.200.124`, policy.Resource{ID: `13`, OwnerID: 1, TenantID: `acme`})
+	assert.NoError(t, err)
+	assert.True(t, got)
+}
@@ -1,29 +1,80 @@
 package auth
 
+import (
+	"context"
+	"time"
+
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
+// ResourceCompany type of resource in policy decisions
+const ResourceCompany = `company`
+
 type Action int
 
 const (
-	ActionCompanyCreate = iota + 1
+	ActionCompanyCreate Action = iota + 1
 	ActionCompanyDelete
+	ActionCompanyUpdate
 )
 
-func (s *service) IsActionAllowed(action Action, ip string) (bool, error) {
-	switch action {
-	case ActionCompanyCreate, ActionCompanyDelete:
-		country, err := s.client.GetRequestLocation(ip)
-		if err != nil {
-			return false, err
+var actionNames = map[Action]string{
+	ActionCompanyCreate: `company_create`,
+	ActionCompanyDelete: `company_delete`,
+	ActionCompanyUpdate: `company_update`,
+}
+
+// String name of the action used in policies
+func (a Action) String() string {
+	if name, ok := actionNames[a]; ok {
+		return name
+	}
+	return `unknown`
+}
+
+func parseAction(name string) (Action, bool) {
+	for action, n := range actionNames {
+		if n == name {
+			return action, true
 		}
-		return isCountryAllowed(action, country), nil
-	default:
+	}
+	return 0, false
+}
+
+// IsActionAllowed checks IP policy and evaluates region and access policies for the action of the user
+// on the company. Actions denied by IP policy are not geolocated.
+func (s *service) IsActionAllowed(ctx context.Context, action Action, user *JWTUser, ip string, resource policy.Resource) (bool, error) {
+	if user == nil {
 		return false, nil
 	}
+	in := policy.Input{
+		Subject: policy.Subject{
+			ID:       user.ID,
+			TenantID: user.TenantID,
+			Roles:    user.Roles,
+			Scopes:   user.Scopes,
+		},
+		Action:   action.String(),
+		Resource: resource,
+		Request: policy.Request{
+			IP:   ip,
+			Time: time.Now(),
+		},
+	}
+	in.Resource.Type = ResourceCompany
+	if reason := s.getIPPolicy().check(action, ip); reason != "" {
+		policy.Audit(ctx, in, policy.Decision{Allowed: false, Reasons: []string{reason}})
+		return false, nil
+	}
+	d := s.engine.Evaluate(ctx, in)
+	return d.Allowed, d.Err
 }
 
-func isCountryAllowed(action Action, country string) bool {
-	switch action {
-	case ActionCompanyCreate, ActionCompanyDelete:
-		return country == "CY"
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
 	}
 	return false
 }
@@ -1,74 +1,50 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/M-Fisher/companies_api/app/internal/policy"
 )
 
-func Test_isCountryAllowed(t *testing.T) {
-	type args struct {
+func Test_service_IsActionAllowedDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
 		action  Action
 		country string
-	}
-	tests := []struct {
-		name string
-		args args
-		want bool
+		want    bool
 	}{
-		{
-			name: `Create from CY - ok`,
-			args: args{
-				action:  ActionCompanyCreate,
-				country: "CY",
-			},
-			want: true,
-		},
-		{
-			name: `Delete from CY - ok`,
-			args: args{
-				action:  ActionCompanyDelete,
-				country: "CY",
-			},
-			want: true,
-		},
-		{
-			name: `Creating from other country not allowed`,
-			args: args{
-				action:  ActionCompanyCreate,
-				country: "US",
-			},
-			want: false,
-		},
-		{
-			name: `Deleting from other country not allowed`,
-			args: args{
-				action:  ActionCompanyDelete,
-				country: "US",
-			},
-			want: false,
-		},
-		{
-			name: `Unknown action not allowed`,
-			args: args{
-				action:  -1,
-				country: "US",
-			},
-			want: false,
-		},
+		{name: `Create from CY - ok`, action: ActionCompanyCreate, country: "CY", want: true},
+		{name: `Delete from CY - ok`, action: ActionCompanyDelete, country: "CY", want: true},
+		{name: `Update from CY - ok`, action: ActionCompanyUpdate, country: "CY", want: true},
+		{name: `Creating from other country not allowed`, action: ActionCompanyCreate, country: "US", want: false},
+		{name: `Deleting from other country not allowed`, action: ActionCompanyDelete, country: "US", want: false},
+		{name: `Unknown action not allowed`, action: -1, country: "CY", want: false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := isCountryAllowed(tt.args.action, tt.args.country)
+			clmock := new(MockIPDataProvider)
+			clmock.On("GetRequestLocation", mock.Anything, `192.200.150.124`).Return(tt.country, nil)
+			s := NewService(clmock)
+
+			got, err := s.IsActionAllowed(context.Background(), tt.action, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+
+			assert.NoError(t, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
 func Test_service_IsActionAllowed(t *testing.T) {
+	user := &JWTUser{ID: 1}
+	admin := &JWTUser{ID: 2, Roles: []string{RoleAdmin}}
 	type args struct {
 		action Action
+		user   *JWTUser
 		ip     string
 	}
 	tests := []struct {
@@ -81,6 +57,7 @@ func Test_service_IsActionAllowed(t *testing.T) {
 			name: `Creating from CY allowed`,
 			args: args{
 				action: ActionCompanyCreate,
+				user:   user,
 				ip:     `192.200.200.124`,
 			},
 			want:    true,
@@ -90,6 +67,7 @@ func Test_service_IsActionAllowed(t *testing.T) {
 			name: `Deleting from CY allowed`,
 			args: args{
 				action: ActionCompanyDelete,
+				user:   user,
 				ip:     `192.200.200.124`,
 			},
 			want:    true,
@@ -99,6 +77,7 @@ func Test_service_IsActionAllowed(t *testing.T) {
 			name: `Creating from US not allowed`,
 			args: args{
 				action: ActionCompanyCreate,
+				user:   user,
 				ip:     `192.200.150.124`,
 			},
 			want:    false,
@@ -108,6 +87,7 @@ func Test_service_IsActionAllowed(t *testing.T) {
 			name: `Deleting from US not allowed`,
 			args: args{
 				action: ActionCompanyDelete,
+				user:   user,
 				ip:     `192.200.150.124`,
 			},
 			want:    false,
@@ -117,15 +97,66 @@ func Test_service_IsActionAllowed(t *testing.T) {
 			name: `Deleting from unknown region not allowed`,
 			args: args{
 				action: ActionCompanyDelete,
+				user:   user,
 				ip:     `192.2`,
 			},
 			want:    false,
 			wantErr: true,
 		},
+		{
+			name: `Updating from CY allowed`,
+			args: args{
+				action: ActionCompanyUpdate,
+				user:   user,
+				ip:     `192.200.200.124`,
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: `Updating from US not allowed`,
+			args: args{
+				action: ActionCompanyUpdate,
+				user:   user,
+				ip:     `192.200.150.124`,
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: `Admin updating from any region allowed`,
+			args: args{
+				action: ActionCompanyUpdate,
+				user:   admin,
+				ip:     `192.2`,
+			},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name: `Admin deleting from US not allowed`,
+			args: args{
+				action: ActionCompanyDelete,
+				user:   admin,
+				ip:     `192.200.150.124`,
+			},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name: `Anonymous user not allowed`,
+			args: args{
+				action: ActionCompanyUpdate,
+				ip:     `192.200.200.124`,
+			},
+			want:    false,
+			wantErr: false,
+		},
 		{
 			name: `Unknown action not allowed`,
 			args: args{
 				action: -1,
+				user:   user,
 				ip:     `200.200.150.124`,
 			},
 			want:    false,
@@ -134,16 +165,14 @@ func Test_service_IsActionAllowed(t *testing.T) {
 	}
 
 	clmock := new(MockIPDataProvider)
-	clmock.On("GetRequestLocation", `192.200.200.124`).Return(`CY`, nil)
-	clmock.On("GetRequestLocation", `192.200.150.124`).Return(`US`, nil)
-	clmock.On("GetRequestLocation", `192.2`).Return(``, errors.New(`invalid ip`))
-	s := &service{
-		client: clmock,
-	}
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.200.124`).Return(`CY`, nil)
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.150.124`).Return(`US`, nil)
+	clmock.On("GetRequestLocation", mock.Anything, `192.2`).Return(``, errors.New(`invalid ip`))
+	s := NewService(clmock)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			got, err := s.IsActionAllowed(tt.args.action, tt.args.ip)
+			got, err := s.IsActionAllowed(context.Background(), tt.args.action, tt.args.user, tt.args.ip, policy.Resource{})
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -1,19 +1,74 @@
 package auth
 
+import (
+	"context"
+	"sync"
+
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
 type AuthService interface {
-	IsActionAllowed(action Action, ip string) (bool, error)
+	// IsActionAllowed decides the action of the user on the resource, resource is empty for creating
+	IsActionAllowed(ctx context.Context, action Action, user *JWTUser, ip string, resource policy.Resource) (bool, error)
+	Authorize(user *JWTUser, scopes ...string) error
 }
 
+// IPDataProvider resolves ISO code of the country of ip, the lookup is abandoned when ctx is done
 type IPDataProvider interface {
-	GetRequestLocation(ip string) (string, error)
+	GetRequestLocation(ctx context.Context, ip string) (string, error)
 }
 
 type service struct {
-	client IPDataProvider
+	client       IPDataProvider
+	engine       *policy.Engine
+	mu           sync.RWMutex
+	regionPolicy RegionPolicy
+	accessPolicy *policy.Policy
+	ipPolicy     IPPolicy
 }
 
 func NewService(ipDataProvider IPDataProvider) *service {
-	return &service{
-		client: ipDataProvider,
+	s := &service{
+		client:       ipDataProvider,
+		engine:       policy.NewEngine(&policy.Policy{}, ipDataProvider),
+		regionPolicy: DefaultRegionPolicy(),
+		accessPolicy: &policy.Policy{},
 	}
+	s.updateEngine()
+	return s
+}
+
+// SetRegionPolicy replaces region policy used for new decisions
+func (s *service) SetRegionPolicy(p RegionPolicy) {
+	s.mu.Lock()
+	s.regionPolicy = p
+	s.mu.Unlock()
+	s.updateEngine()
+}
+
+// SetAccessPolicy replaces rules evaluated together with region policy
+func (s *service) SetAccessPolicy(p *policy.Policy) {
+	s.mu.Lock()
+	s.accessPolicy = p
+	s.mu.Unlock()
+	s.updateEngine()
+}
+
+// SetIPPolicy replaces IP rules checked before region and access policies
+func (s *service) SetIPPolicy(p IPPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ipPolicy = p
+}
+
+func (s *service) getIPPolicy() IPPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ipPolicy
+}
+
+func (s *service) updateEngine() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.engine.SetPolicy(policy.Merge(s.regionPolicy.accessPolicy(), s.accessPolicy))
 }
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+// ErrUnknownCertificate verified client certificate is not mapped to any principal
+var ErrUnknownCertificate = errors.New(`unknown client certificate`)
+
+// CertPrincipal user authenticated by client certificate. The certificate matches
+// the principal by subject common name or by any of DNS, URI or email SAN.
+type CertPrincipal struct {
+	CommonName string   `json:"common_name"`
+	DNSName    string   `json:"dns_name"`
+	URI        string   `json:"uri"`
+	Email      string   `json:"email"`
+	UserID     int64    `json:"user_id"`
+	TenantID   string   `json:"tenant_id"`
+	Roles      []string `json:"roles"`
+	Scopes     []string `json:"scopes"`
+}
+
+type CertPrincipals []CertPrincipal
+
+func ParseCertPrincipals(data []byte) (CertPrincipals, error) {
+	var p CertPrincipals
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse client principals: %w", err)
+	}
+	for i, principal := range p {
+		if principal.CommonName == "" && principal.DNSName == "" && principal.URI == "" && principal.Email == "" {
+			return nil, fmt.Errorf("client principal %d doesn't match any certificate", i)
+		}
+		if principal.TenantID == "" {
+			p[i].TenantID = identity.DefaultTenant
+		}
+	}
+	return p, nil
+}
+
+func LoadCertPrincipals(file string) (CertPrincipals, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client principals file: %w", err)
+	}
+	return ParseCertPrincipals(data)
+}
+
+// User returns user of the first principal matching the certificate, the certificate must be verified by caller
+func (p CertPrincipals) User(cert *x509.Certificate) (*JWTUser, error) {
+	for _, principal := range p {
+		if principal.matches(cert) {
+			return &JWTUser{
+				ID:       principal.UserID,
+				TenantID: principal.TenantID,
+				Roles:    principal.Roles,
+				Scopes:   grantedScopes(principal.Roles, principal.Scopes, nil),
+			}, nil
+		}
+	}
+	return nil, ErrUnknownCertificate
+}
+
+func (cp *CertPrincipal) matches(cert *x509.Certificate) bool {
+	switch {
+	case cp.CommonName != "" && cp.CommonName == cert.Subject.CommonName:
+		return true
+	case cp.DNSName != "" && contains(cert.DNSNames, cp.DNSName):
+		return true
+	case cp.Email != "" && contains(cert.EmailAddresses, cp.Email):
+		return true
+	case cp.URI != "":
+		for _, uri := range cert.URIs {
+			if uri.String() == cp.URI {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCertPrincipals(t *testing.T) {
+	got, err := ParseCertPrincipals([]byte(`[{"common_name": "billing", "user_id": 1001, "scopes": ["companies:read"]}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, CertPrincipals{{CommonName: `billing`, UserID: 1001, TenantID: `default`, Scopes: []string{ScopeCompaniesRead}}}, got)
+
+	_, err = ParseCertPrincipals([]byte(`[{"user_id": 1001}]`))
+	assert.Error(t, err)
+}
+
+func TestCertPrincipals_User(t *testing.T) {
+	spiffe, _ := url.Parse(`spiffe://internal/importer`)
+	principals := CertPrincipals{
+		{CommonName: `billing`, UserID: 1001, TenantID: `default`, Scopes: []string{ScopeCompaniesRead}},
+		{DNSName: `reports.internal`, UserID: 1002, TenantID: `acme`, Roles: []string{RoleAdmin}},
+		{URI: `spiffe://internal/importer`, UserID: 1003, TenantID: `default`, Scopes: []string{ScopeCompaniesWrite}},
+		{Email: `ops@example.com`, UserID: 1004, TenantID: `default`, Scopes: []string{ScopeCompaniesDelete}},
+	}
+	tests := []struct {
+		name    string
+		cert    *x509.Certificate
+		want    *JWTUser
+		wantErr error
+	}{
+		{
+			name: `Common name`,
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: `billing`}},
+			want: &JWTUser{ID: 1001, TenantID: `default`, Scopes: []string{ScopeCompaniesRead}},
+		},
+		{
+			name: `DNS SAN with role`,
+			cert: &x509.Certificate{Subject: pkix.Name{CommonName: `reports`}, DNSNames: []string{`reports.internal`}},
+			want: &JWTUser{ID: 1002, TenantID: `acme`, Roles: []string{RoleAdmin}, Scopes: roleScopes[RoleAdmin]},
+		},
+		{
+			name: `URI SAN`,
+			cert: &x509.Certificate{URIs: []*url.URL{spiffe}},
+			want: &JWTUser{ID: 1003, TenantID: `default`, Scopes: []string{ScopeCompaniesWrite}},
+		},
+		{
+			name: `Email SAN`,
+			cert: &x509.Certificate{EmailAddresses: []string{`ops@example.com`}},
+			want: &JWTUser{ID: 1004, TenantID: `default`, Scopes: []string{ScopeCompaniesDelete}},
+		},
+		{
+			name:    `Unknown certificate`,
+			cert:    &x509.Certificate{Subject: pkix.Name{CommonName: `other`}, DNSNames: []string{`other.internal`}},
+			wantErr: ErrUnknownCertificate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := principals.User(tt.cert)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
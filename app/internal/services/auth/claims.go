@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrTokenExpired       = errors.New(`token is expired`)
+	ErrTokenNotValidYet   = errors.New(`token is not valid yet`)
+	ErrTokenTooOld        = errors.New(`token is too old`)
+	ErrTokenExpiryMissing = errors.New(`token has no expiry`)
+	ErrInvalidIssuer      = errors.New(`invalid token issuer`)
+	ErrInvalidAudience    = errors.New(`invalid token audience`)
+)
+
+// ClaimsValidation checks of registered JWT claims, empty values disable the checks
+type ClaimsValidation struct {
+	Issuer   string
+	Audience string
+	// MaxAge maximum time since the token was issued, requires iat claim
+	MaxAge time.Duration
+	// Leeway allowed clock skew between the issuer and the service
+	Leeway        time.Duration
+	RequireExpiry bool
+	// LegacyScopes granted to tokens which carry neither roles nor scopes, such tokens get no scopes if empty
+	LegacyScopes []string
+}
+
+// audience aud claim, string or array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Valid implements jwt.Claims, claims are validated by validate with configured options
+func (c *JWTClaims) Valid() error {
+	return nil
+}
+
+func (c *JWTClaims) validate(opts ClaimsValidation, now time.Time) error {
+	unix := now.Unix()
+	leeway := int64(opts.Leeway.Seconds())
+	switch {
+	case c.ExpiresAt == 0 && opts.RequireExpiry:
+		return ErrTokenExpiryMissing
+	case c.ExpiresAt != 0 && unix > c.ExpiresAt+leeway:
+		return ErrTokenExpired
+	case c.NotBefore != 0 && unix < c.NotBefore-leeway:
+		return ErrTokenNotValidYet
+	case c.IssuedAt != 0 && unix < c.IssuedAt-leeway:
+		return ErrTokenNotValidYet
+	}
+	if opts.MaxAge > 0 && (c.IssuedAt == 0 || unix > c.IssuedAt+int64(opts.MaxAge.Seconds())+leeway) {
+		return ErrTokenTooOld
+	}
+	if opts.Issuer != "" && c.Issuer != opts.Issuer {
+		return ErrInvalidIssuer
+	}
+	if opts.Audience != "" && !contains(c.Audience, opts.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTClaims_validate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	unix := now.Unix()
+	tests := []struct {
+		name    string
+		claims  JWTClaims
+		opts    ClaimsValidation
+		wantErr error
+	}{
+		{
+			name:   "No claims",
+			claims: JWTClaims{},
+		},
+		{
+			name:    "Expired",
+			claims:  JWTClaims{ExpiresAt: unix - 60},
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:   "Expired within leeway",
+			claims: JWTClaims{ExpiresAt: unix - 20},
+			opts:   ClaimsValidation{Leeway: 30 * time.Second},
+		},
+		{
+			name:    "Expiry required",
+			claims:  JWTClaims{},
+			opts:    ClaimsValidation{RequireExpiry: true},
+			wantErr: ErrTokenExpiryMissing,
+		},
+		{
+			name:    "Not valid yet",
+			claims:  JWTClaims{ExpiresAt: unix + 60, NotBefore: unix + 60},
+			opts:    ClaimsValidation{Leeway: 30 * time.Second},
+			wantErr: ErrTokenNotValidYet,
+		},
+		{
+			name:    "Issued in future",
+			claims:  JWTClaims{IssuedAt: unix + 60},
+			wantErr: ErrTokenNotValidYet,
+		},
+		{
+			name:    "Too old",
+			claims:  JWTClaims{IssuedAt: unix - 3600},
+			opts:    ClaimsValidation{MaxAge: 30 * time.Minute},
+			wantErr: ErrTokenTooOld,
+		},
+		{
+			name:    "Max age without iat",
+			claims:  JWTClaims{},
+			opts:    ClaimsValidation{MaxAge: 30 * time.Minute},
+			wantErr: ErrTokenTooOld,
+		},
+		{
+			name:   "Fresh",
+			claims: JWTClaims{IssuedAt: unix - 60, ExpiresAt: unix + 60},
+			opts:   ClaimsValidation{MaxAge: 30 * time.Minute, RequireExpiry: true},
+		},
+		{
+			name:    "Wrong issuer",
+			claims:  JWTClaims{Issuer: `https://other.example.com`},
+			opts:    ClaimsValidation{Issuer: `https://auth.example.com`},
+			wantErr: ErrInvalidIssuer,
+		},
+		{
+			name:    "Wrong audience",
+			claims:  JWTClaims{Issuer: `https://auth.example.com`, Audience: audience{`billing`}},
+			opts:    ClaimsValidation{Issuer: `https://auth.example.com`, Audience: `companies`},
+			wantErr: ErrInvalidAudience,
+		},
+		{
+			name:   "One of audiences",
+			claims: JWTClaims{Audience: audience{`billing`, `companies`}},
+			opts:   ClaimsValidation{Audience: `companies`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.claims.validate(tt.opts, now)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJWTUser_ParseWithKeysClaims(t *testing.T) {
+	sign := func(claims jwt.MapClaims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(`test`))
+		require.NoError(t, err)
+		return signed
+	}
+	keys := &JWTKeys{Secret: `test`}
+	opts := ClaimsValidation{Audience: `companies`, RequireExpiry: true}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	user, err := new(JWTUser).ParseWithKeys(context.Background(), sign(jwt.MapClaims{"user_id": "1", "aud": "companies", "exp": exp}), keys, opts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), sign(jwt.MapClaims{"user_id": "1", "aud": []string{"billing", "companies"}, "exp": exp}), keys, opts)
+	assert.NoError(t, err)
+
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), sign(jwt.MapClaims{"user_id": "1", "aud": "billing", "exp": exp}), keys, opts)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), sign(jwt.MapClaims{"user_id": "1", "aud": "companies", "exp": time.Now().Add(-time.Hour).Unix()}), keys, opts)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
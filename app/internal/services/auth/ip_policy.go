@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+// IPRule networks the action may be performed from
+type IPRule struct {
+	// Allow CIDRs or addresses allowed to perform the action, any address is allowed if empty
+	Allow []string `json:"allow"`
+	// Deny CIDRs or addresses denied even if they are allowed
+	Deny []string `json:"deny"`
+
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// IPPolicy IP rules by action name, actions without rule are not limited by IP
+type IPPolicy struct {
+	Rules map[string]IPRule
+	// AllowPrivate private and loopback addresses bypass the rules, for development environments
+	AllowPrivate bool
+}
+
+// ParseIPPolicy parses JSON rules and validates their action names and networks
+func ParseIPPolicy(data []byte) (IPPolicy, error) {
+	var rules map[string]IPRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return IPPolicy{}, fmt.Errorf("failed to parse IP policy: %w", err)
+	}
+	for name, rule := range rules {
+		if _, ok := parseAction(name); !ok {
+			return IPPolicy{}, fmt.Errorf("unknown action in IP policy: %s", name)
+		}
+		var err error
+		if rule.allow, err = parseNetworks(rule.Allow); err != nil {
+			return IPPolicy{}, fmt.Errorf("invalid IP policy of %s: %w", name, err)
+		}
+		if rule.deny, err = parseNetworks(rule.Deny); err != nil {
+			return IPPolicy{}, fmt.Errorf("invalid IP policy of %s: %w", name, err)
+		}
+		rules[name] = rule
+	}
+	return IPPolicy{Rules: rules}, nil
+}
+
+// LoadIPPolicy loads policy from the file or from the env, no action is limited if none is configured
+func LoadIPPolicy(conf *config.IPPolicy) (IPPolicy, error) {
+	var (
+		p   IPPolicy
+		err error
+	)
+	switch {
+	case conf.File != "":
+		data, readErr := os.ReadFile(conf.File)
+		if readErr != nil {
+			return IPPolicy{}, fmt.Errorf("failed to read IP policy file: %w", readErr)
+		}
+		p, err = ParseIPPolicy(data)
+	case conf.Rules != "":
+		p, err = ParseIPPolicy([]byte(conf.Rules))
+	}
+	if err != nil {
+		return IPPolicy{}, err
+	}
+	p.AllowPrivate = conf.AllowPrivate
+	return p, nil
+}
+
+// WatchIPPolicy reloads the policy file when it is modified. Invalid policy is logged
+// and the previous one is kept. Returned function stops watching.
+func (s *service) WatchIPPolicy(conf *config.IPPolicy, log *zap.Logger) func() {
+	return watchFile(conf.File, conf.ReloadInterval, log.With(zap.String("ip_policy_file", conf.File)), func() error {
+		p, err := LoadIPPolicy(conf)
+		if err != nil {
+			return err
+		}
+		s.SetIPPolicy(p)
+		return nil
+	})
+}
+
+// check returns the rule which denies the action from the ip, empty if it is allowed
+func (p IPPolicy) check(action Action, ip string) string {
+	rule, ok := p.Rules[action.String()]
+	if !ok || len(rule.allow)+len(rule.deny) == 0 {
+		return ""
+	}
+	prefix := `ip:` + action.String()
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return prefix + `:invalid_address`
+	}
+	if p.AllowPrivate && (addr.IsPrivate() || addr.IsLoopback()) {
+		return ""
+	}
+	if containsIP(rule.deny, addr) {
+		return prefix + `:deny`
+	}
+	if len(rule.allow) > 0 && !containsIP(rule.allow, addr) {
+		return prefix + `:not_allowed`
+	}
+	return ""
+}
+
+func parseNetworks(list []string) ([]*net.IPNet, error) {
+	res := make([]*net.IPNet, 0, len(list))
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address: %q", v)
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			res = append(res, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, network)
+	}
+	return res, nil
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
+func TestParseIPPolicy(t *testing.T) {
+	p, err := ParseIPPolicy([]byte(`{
+		"company_create": {"allow": ["10.0.0.0/8", "2001:db8::/32"], "deny": ["10.6.6.6"]}
+	}`))
+	assert.NoError(t, err)
+	assert.Len(t, p.Rules[`company_create`].allow, 2)
+	assert.Len(t, p.Rules[`company_create`].deny, 1)
+
+	_, err = ParseIPPolicy([]byte(`{"company_merge": {"allow": ["10.0.0.0/8"]}}`))
+	assert.EqualError(t, err, `unknown action in IP policy: company_merge`)
+
+	_, err = ParseIPPolicy([]byte(`{"company_create": {"deny": ["10.0.0.0/33"]}}`))
+	assert.Error(t, err)
+
+	_, err = ParseIPPolicy([]byte(`{"company_create": {"allow": ["office"]}}`))
+	assert.EqualError(t, err, `invalid IP policy of company_create: invalid address: "office"`)
+}
+
+func TestIPPolicy_check(t *testing.T) {
+	p, err := ParseIPPolicy([]byte(`{
+		"company_create": {"allow": ["203.0.113.0/24", "2001:db8::/32"], "deny": ["203.0.113.66"]},
+		"company_delete": {"deny": ["198.51.100.0/24"]}
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		action       Action
+		ip           string
+		allowPrivate bool
+		want         string
+	}{
+		{name: `Allowed network`, action: ActionCompanyCreate, ip: `203.0.113.7`},
+		{name: `Allowed IPv6 network`, action: ActionCompanyCreate, ip: `2001:db8::7`},
+		{name: `Not in allowed networks`, action: ActionCompanyCreate, ip: `192.0.2.1`, want: `ip:company_create:not_allowed`},
+		{name: `Denied address in allowed network`, action: ActionCompanyCreate, ip: `203.0.113.66`, want: `ip:company_create:deny`},
+		{name: `Invalid address`, action: ActionCompanyCreate, ip: `203.0.113.7:80`, want: `ip:company_create:invalid_address`},
+		{name: `Denied network`, action: ActionCompanyDelete, ip: `198.51.100.1`, want: `ip:company_delete:deny`},
+		{name: `Not denied`, action: ActionCompanyDelete, ip: `192.0.2.1`},
+		{name: `Action without rule`, action: ActionCompanyUpdate, ip: `198.51.100.1`},
+		{name: `Private address`, action: ActionCompanyCreate, ip: `192.168.1.1`, want: `ip:company_create:not_allowed`},
+		{name: `Private address bypass`, action: ActionCompanyCreate, ip: `192.168.1.1`, allowPrivate: true},
+		{name: `Loopback bypass`, action: ActionCompanyCreate, ip: `::1`, allowPrivate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.AllowPrivate = tt.allowPrivate
+			assert.Equal(t, tt.want, p.check(tt.action, tt.ip))
+		})
+	}
+}
+
+func Test_service_IsActionAllowedIPPolicy(t *testing.T) {
+	clmock := new(MockIPDataProvider)
+	clmock.On("GetRequestLocation", mock.Anything, `203.0.113.7`).Return(`CY`, nil)
+	s := NewService(clmock)
+	p, err := ParseIPPolicy([]byte(`{"company_create": {"allow": ["203.0.113.0/24"]}}`))
+	require.NoError(t, err)
+	s.SetIPPolicy(p)
+
+	got, err := s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1}, `198.51.100.1`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.False(t, got)
+	clmock.AssertNotCalled(t, "GetRequestLocation", mock.Anything, mock.Anything)
+
+	// allowed networks are still checked by region policy
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1}, `203.0.113.7`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.True(t, got)
+	clmock.AssertCalled(t, "GetRequestLocation", mock.Anything, `203.0.113.7`)
+}
+
+func TestLoadIPPolicy(t *testing.T) {
+	p, err := LoadIPPolicy(&config.IPPolicy{AllowPrivate: true})
+	assert.NoError(t, err)
+	assert.Empty(t, p.Rules)
+	assert.True(t, p.AllowPrivate)
+
+	p, err = LoadIPPolicy(&config.IPPolicy{Rules: `{"company_delete": {"deny": ["198.51.100.0/24"]}}`})
+	assert.NoError(t, err)
+	assert.Equal(t, `ip:company_delete:deny`, p.check(ActionCompanyDelete, `198.51.100.1`))
+
+	_, err = LoadIPPolicy(&config.IPPolicy{File: filepath.Join(t.TempDir(), `missing.json`)})
+	assert.Error(t, err)
+}
+
+func Test_service_WatchIPPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), `ip_policy.json`)
+	if err := os.WriteFile(file, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.IPPolicy{File: file, ReloadInterval: 10 * time.Millisecond}
+	s := NewService(new(MockIPDataProvider))
+	stop := s.WatchIPPolicy(conf, zap.NewNop())
+	defer stop()
+
+	if err := os.WriteFile(file, []byte(`{"company_delete": {"deny": ["198.51.100.0/24"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Eventually(t, func() bool {
+		return s.getIPPolicy().check(ActionCompanyDelete, `198.51.100.1`) != ""
+	}, time.Second, 10*time.Millisecond)
+}
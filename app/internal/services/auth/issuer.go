@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var ErrSigningDisabled = errors.New(`token signing is not configured`)
+
+// TokenIssuer signs tokens issued by the service with HMAC secret
+type TokenIssuer struct {
+	Secret   string
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+}
+
+// Issue signs the claims setting registered claims, the token expires after TTL
+func (ti *TokenIssuer) Issue(claims JWTClaims) (string, error) {
+	if ti.Secret == "" {
+		return "", ErrSigningDisabled
+	}
+	now := jwt.TimeFunc()
+	claims.Issuer = ti.Issuer
+	if ti.Audience != "" {
+		claims.Audience = audience{ti.Audience}
+	}
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("generate jti: %w", err)
+	}
+	claims.JTI = hex.EncodeToString(jti)
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(ti.TTL).Unix()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString([]byte(ti.Secret))
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return token, nil
+}
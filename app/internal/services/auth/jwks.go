@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// minJWKSRefreshInterval limits refreshes triggered by tokens with unknown key id
+const minJWKSRefreshInterval = 30 * time.Second
+
+var (
+	ErrUnknownKey   = errors.New(`unknown signing key`)
+	errNoUsableKeys = errors.New(`JWKS has no usable signing keys`)
+)
+
+// jwk JSON Web Key, RFC 7517
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	alg string
+	key interface{}
+}
+
+// JWKS public keys for verification of asymmetric tokens, loaded from a file or URL.
+// Keys are refreshed periodically and when a token is signed by unknown key.
+type JWKS struct {
+	source string
+	client *http.Client
+	log    *zap.Logger
+	group  singleflight.Group
+	mu     sync.RWMutex
+	keys   map[string]publicKey
+	// attemptedAt time of the last refresh, successful or not
+	attemptedAt time.Time
+}
+
+// NewJWKS loads keys from source, http(s) URL or path to a file
+func NewJWKS(source string, timeout time.Duration, log *zap.Logger) (*JWKS, error) {
+	j := &JWKS{
+		source: source,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+		log: log.With(zap.String("jwks", source)),
+	}
+	if err := j.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Refresh reloads keys from the source, previous keys are kept on failure
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.mu.Lock()
+	j.attemptedAt = time.Now()
+	j.mu.Unlock()
+
+	data, err := j.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %w", err)
+	}
+	keys, err := parseJWKS(data, j.log)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.keys = keys
+	return nil
+}
+
+// Watch refreshes keys on interval. Returned function stops watching.
+func (j *JWKS) Watch(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := j.Refresh(context.Background()); err != nil {
+					j.log.Error("Failed to refresh JWKS, previous keys are kept", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+func (j *JWKS) fetch(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(j.source, "file://"))
+	}
+	r, err := http.NewRequestWithContext(ctx, `GET`, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// key returns verification key of the token by its kid. Unknown kid triggers refresh,
+// so keys rotated by the issuer are picked up before the next periodic refresh.
+func (j *JWKS) key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.lookup(kid)
+	if !ok {
+		j.refreshUnknown(ctx)
+		key, ok = j.lookup(kid)
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if key.alg != "" && key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("key %s is not for %s", kid, token.Method.Alg())
+	}
+	if !keyMatchesMethod(key.key, token.Method) {
+		return nil, fmt.Errorf("key %s does not match signing method %s", kid, token.Method.Alg())
+	}
+	return key.key, nil
+}
+
+func (j *JWKS) lookup(kid string) (publicKey, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// refreshUnknown refreshes keys at most once per minJWKSRefreshInterval whether the source is up or not.
+// Concurrent callers share one refresh, a caller stops waiting when its ctx is done.
+func (j *JWKS) refreshUnknown(ctx context.Context) {
+	ch := j.group.DoChan(`refresh`, func() (interface{}, error) {
+		if !j.refreshAllowed() {
+			return nil, nil
+		}
+		if err := j.Refresh(context.Background()); err != nil {
+			j.log.Error("Failed to refresh JWKS", zap.Error(err))
+		}
+		return nil, nil
+	})
+	select {
+	case <-ctx.Done():
+	case <-ch:
+	}
+}
+
+func (j *JWKS) refreshAllowed() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return time.Since(j.attemptedAt) >= minJWKSRefreshInterval
+}
+
+func keyMatchesMethod(key interface{}, method jwt.SigningMethod) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			return true
+		}
+	case *ecdsa.PublicKey:
+		if m, ok := method.(*jwt.SigningMethodECDSA); ok {
+			return k.Curve.Params().BitSize == m.CurveBits
+		}
+	case ed25519.PublicKey:
+		_, ok := method.(*jwt.SigningMethodEd25519)
+		return ok
+	}
+	return false
+}
+
+// parseJWKS returns usable signing keys of the set. Keys of unsupported type or invalid keys are skipped,
+// so one key the service can't use doesn't prevent verification with the rest.
+func parseJWKS(data []byte, log *zap.Logger) (map[string]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Warn("Skipping invalid key in JWKS", zap.String("kid", k.Kid), zap.Error(err))
+			continue
+		}
+		if key == nil {
+			continue
+		}
+		keys[k.Kid] = publicKey{alg: k.Alg, key: key}
+	}
+	if len(keys) == 0 {
+		return nil, errNoUsableKeys
+	}
+	return keys, nil
+}
+
+// publicKey of the JWK, nil for unsupported key types
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) jwk {
+	return jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: b64(key.X.Bytes()), Y: b64(key.Y.Bytes())}
+}
+
+func edJWK(kid string, key ed25519.PrivateKey) jwk {
+	return jwk{Kty: "OKP", Kid: kid, Crv: "Ed25519", X: b64(key.Public().(ed25519.PublicKey))}
+}
+
+func jwksJSON(t *testing.T, keys ...jwk) []byte {
+	data, err := json.Marshal(map[string][]jwk{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func writeJWKS(t *testing.T, keys ...jwk) string {
+	file := filepath.Join(t.TempDir(), `jwks.json`)
+	require.NoError(t, os.WriteFile(file, jwksJSON(t, keys...), 0o600))
+	return file
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	token := jwt.NewWithClaims(method, &JWTClaims{ID: "7"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWTUser_ParseWithKeysJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks, err := NewJWKS(writeJWKS(t, rsaJWK("rsa", rsaKey), ecJWK("ec", ecKey), edJWK("ed", edKey)), time.Second, zap.NewNop())
+	require.NoError(t, err)
+	keys := &JWTKeys{Secret: `test`, JWKS: jwks}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  `RS256`,
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey),
+		},
+		{
+			name:  `ES256`,
+			token: signToken(t, jwt.SigningMethodES256, "ec", ecKey),
+		},
+		{
+			name:  `EdDSA`,
+			token: signToken(t, jwt.SigningMethodEdDSA, "ed", edKey),
+		},
+		{
+			name:  `HMAC`,
+			token: signToken(t, jwt.SigningMethodHS256, "", []byte(`test`)),
+		},
+		{
+			name:    `Signed by other key`,
+			token:   signToken(t, jwt.SigningMethodRS256, "rsa", otherKey),
+			wantErr: true,
+		},
+		{
+			name:    `Unknown kid`,
+			token:   signToken(t, jwt.SigningMethodRS256, "other", otherKey),
+			wantErr: true,
+		},
+		{
+			name:    `Algorithm doesn't match key`,
+			token:   signToken(t, jwt.SigningMethodRS512, "rsa", rsaKey),
+			wantErr: true,
+		},
+		{
+			name:    `Kid is required with several keys`,
+			token:   signToken(t, jwt.SigningMethodRS256, "", rsaKey),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := new(JWTUser).ParseWithKeys(context.Background(), tt.token, keys, ClaimsValidation{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int64(7), got.ID)
+		})
+	}
+}
+
+func TestJWTKeys_HMACDisabled(t *testing.T) {
+	_, err := new(JWTUser).ParseWithKeys(context.Background(), signToken(t, jwt.SigningMethodHS256, "", []byte(`test`)), &JWTKeys{}, ClaimsValidation{})
+	assert.Error(t, err)
+}
+
+func TestJWKS_Rotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		rotated  atomic.Bool
+		requests atomic.Int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if rotated.Load() {
+			_, _ = w.Write(jwksJSON(t, rsaJWK("new", newKey)))
+			return
+		}
+		_, _ = w.Write(jwksJSON(t, rsaJWK("old", oldKey)))
+	}))
+	defer srv.Close()
+
+	jwks, err := NewJWKS(srv.URL, time.Second, zap.NewNop())
+	require.NoError(t, err)
+	keys := &JWTKeys{JWKS: jwks}
+
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), signToken(t, jwt.SigningMethodRS256, "old", oldKey), keys, ClaimsValidation{})
+	assert.NoError(t, err)
+
+	rotated.Store(true)
+	// refresh by unknown kid is rate limited
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), signToken(t, jwt.SigningMethodRS256, "new", newKey), keys, ClaimsValidation{})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+
+	jwks.mu.Lock()
+	jwks.attemptedAt = time.Now().Add(-minJWKSRefreshInterval)
+	jwks.mu.Unlock()
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), signToken(t, jwt.SigningMethodRS256, "new", newKey), keys, ClaimsValidation{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), signToken(t, jwt.SigningMethodRS256, "old", oldKey), keys, ClaimsValidation{})
+	assert.Error(t, err)
+}
+
+func TestJWKS_RefreshWhileSourceIsDown(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		down     atomic.Bool
+		requests atomic.Int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			requests.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(jwksJSON(t, rsaJWK("old", key)))
+	}))
+	defer srv.Close()
+
+	jwks, err := NewJWKS(srv.URL, time.Second, zap.NewNop())
+	require.NoError(t, err)
+	keys := &JWTKeys{JWKS: jwks}
+	down.Store(true)
+	jwks.mu.Lock()
+	jwks.attemptedAt = time.Now().Add(-minJWKSRefreshInterval)
+	jwks.mu.Unlock()
+
+	token := signToken(t, jwt.SigningMethodRS256, "unknown", key)
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := new(JWTUser).ParseWithKeys(context.Background(), token, keys, ClaimsValidation{})
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		assert.Error(t, <-errs)
+	}
+	// concurrent lookups share one refresh
+	assert.Equal(t, int32(1), requests.Load())
+
+	// failed refresh is rate limited too
+	_, err = new(JWTUser).ParseWithKeys(context.Background(), token, keys, ClaimsValidation{})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestJWKS_KeyContextDone(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	release := make(chan struct{})
+	var slow atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			<-release
+		}
+		_, _ = w.Write(jwksJSON(t, rsaJWK("old", key)))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	jwks, err := NewJWKS(srv.URL, 5*time.Second, zap.NewNop())
+	require.NoError(t, err)
+	slow.Store(true)
+	jwks.mu.Lock()
+	jwks.attemptedAt = time.Now().Add(-minJWKSRefreshInterval)
+	jwks.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = new(JWTUser).ParseWithKeys(ctx, signToken(t, jwt.SigningMethodRS256, "unknown", key), &JWTKeys{JWKS: jwks}, ClaimsValidation{})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestNewJWKSErrors(t *testing.T) {
+	_, err := NewJWKS(filepath.Join(t.TempDir(), `missing.json`), time.Second, zap.NewNop())
+	assert.Error(t, err)
+
+	_, err = NewJWKS(writeJWKS(t, jwk{Kty: "oct", Kid: "sym"}), time.Second, zap.NewNop())
+	assert.ErrorIs(t, err, errNoUsableKeys)
+
+	_, err = NewJWKS(writeJWKS(t, jwk{Kty: "EC", Kid: "bad", Crv: "P-256", X: b64([]byte{1}), Y: b64([]byte{2})}), time.Second, zap.NewNop())
+	assert.ErrorIs(t, err, errNoUsableKeys)
+}
+
+func TestJWKS_SkipsInvalidKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	core, logs := observer.New(zapcore.WarnLevel)
+
+	jwks, err := NewJWKS(writeJWKS(t,
+		jwk{Kty: "EC", Kid: "secp256k1", Crv: "secp256k1", X: b64([]byte{1}), Y: b64([]byte{2})},
+		jwk{Kty: "EC", Kid: "bad", Crv: "P-256", X: b64([]byte{1}), Y: b64([]byte{2})},
+		rsaJWK("rsa", rsaKey),
+	), time.Second, zap.New(core))
+	require.NoError(t, err)
+
+	user, err := new(JWTUser).ParseWithKeys(context.Background(), signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey), &JWTKeys{JWKS: jwks}, ClaimsValidation{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), user.ID)
+
+	entries := logs.FilterMessage("Skipping invalid key in JWKS").All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "secp256k1", entries[0].ContextMap()["kid"])
+	assert.Equal(t, "bad", entries[1].ContextMap()["kid"])
+}
@@ -1,49 +1,139 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
 )
 
 // JWTClaims is a struct of JWT payload
 type JWTClaims struct {
-	ID string `json:"user_id"`
-	jwt.StandardClaims
+	ID       string   `json:"user_id"`
+	TenantID string   `json:"tenant_id"`
+	Roles    []string `json:"roles"`
+	// Scope space separated list of scopes
+	Scope string `json:"scope"`
+	// ClientID client id of the service account the token was issued to
+	ClientID string `json:"client_id,omitempty"`
+	// JTI unique id of the token, tokens are revoked by it
+	JTI       string   `json:"jti,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
 }
 
 type JWTUser struct {
-	JWT string `json:"jwt"`
-	ID  int64  `json:"id"`
+	JWT      string   `json:"jwt"`
+	ID       int64    `json:"id"`
+	TenantID string   `json:"tenant_id"`
+	Roles    []string `json:"roles"`
+	// Scopes granted to the user by the token and its roles
+	Scopes []string `json:"scopes"`
+	// ClientID set if the user is a service account
+	ClientID string `json:"client_id,omitempty"`
+	// APIKeyID set if the user is authenticated by API key
+	APIKeyID int64 `json:"-"`
+	// TokenID, IssuedAt and ExpiresAt of the token, zero if the token doesn't have them
+	TokenID   string    `json:"-"`
+	IssuedAt  time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// KeyProvider resolves the key verifying signature of the token, lookups are abandoned when ctx is done
+type KeyProvider interface {
+	Key(ctx context.Context, token *jwt.Token) (interface{}, error)
 }
 
-// Parse JWT into JWTUser
+// JWTKeys verifies HMAC tokens with the shared secret and asymmetric tokens with JWKS keys.
+// Empty secret disables HMAC, nil JWKS disables asymmetric tokens.
+type JWTKeys struct {
+	Secret string
+	JWKS   *JWKS
+}
+
+func (k *JWTKeys) Key(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if k.Secret == "" {
+			return nil, errors.New("HMAC tokens are not accepted")
+		}
+		return []byte(k.Secret), nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		if k.JWKS == nil {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return k.JWKS.key(ctx, token)
+	}
+	return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+}
+
+// Parse HMAC signed JWT into JWTUser
 func (ju *JWTUser) Parse(jwtToken, jwtSecret string) (*JWTUser, error) {
+	return ju.ParseWithKeys(context.Background(), jwtToken, &JWTKeys{Secret: jwtSecret}, ClaimsValidation{})
+}
+
+// ParseWithKeys parses JWT into JWTUser verifying its signature with the keys and validating its claims
+func (ju *JWTUser) ParseWithKeys(ctx context.Context, jwtToken string, keys KeyProvider, opts ClaimsValidation) (*JWTUser, error) {
 	if len(jwtToken) <= 0 {
 		return nil, errors.New("auth parsing error: JWT is empty")
 	}
 
 	token, err := jwt.ParseWithClaims(jwtToken, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
-		return []byte(jwtSecret), nil
+		return keys.Key(ctx, token)
 	})
 	if err != nil {
 		return nil, fmt.Errorf(`auth error: %w`, err)
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		if err := claims.validate(opts, jwt.TimeFunc()); err != nil {
+			return nil, fmt.Errorf(`auth error: %w`, err)
+		}
 		id, err := strconv.ParseInt(claims.ID, 10, 64)
 		if err != nil {
 			return nil, fmt.Errorf(`auth error: %w`, err)
 		}
 
-		ju = &JWTUser{JWT: jwtToken, ID: id}
+		tenantID := claims.TenantID
+		if tenantID == "" {
+			tenantID = identity.DefaultTenant
+		}
+
+		ju = &JWTUser{
+			JWT:      jwtToken,
+			ID:       id,
+			TenantID: tenantID,
+			Roles:    claims.Roles,
+			Scopes:   grantedScopes(claims.Roles, strings.Fields(claims.Scope), opts.LegacyScopes),
+			ClientID: claims.ClientID,
+			TokenID:  claims.JTI,
+		}
+		if claims.IssuedAt != 0 {
+			ju.IssuedAt = time.Unix(claims.IssuedAt, 0)
+		}
+		if claims.ExpiresAt != 0 {
+			ju.ExpiresAt = time.Unix(claims.ExpiresAt, 0)
+		}
 		return ju, nil
 	}
 	return nil, fmt.Errorf(`auth error: %w`, err)
 }
+
+// Identity of the user for request context
+func (ju *JWTUser) Identity() identity.Identity {
+	return identity.Identity{
+		UserID:   ju.ID,
+		TenantID: ju.TenantID,
+		Roles:    ju.Roles,
+		APIKeyID: ju.APIKeyID,
+	}
+}
@@ -2,27 +2,54 @@
 
 package auth
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	policy "github.com/M-Fisher/companies_api/app/internal/policy"
+)
 
 // MockAuthService is an autogenerated mock type for the AuthService type
 type MockAuthService struct {
 	mock.Mock
 }
 
-// IsActionAllowed provides a mock function with given fields: action, ip
-func (_m *MockAuthService) IsActionAllowed(action Action, ip string) (bool, error) {
-	ret := _m.Called(action, ip)
+// Authorize provides a mock function with given fields: user, scopes
+func (_m *MockAuthService) Authorize(user *JWTUser, scopes ...string) error {
+	_va := make([]interface{}, len(scopes))
+	for _i := range scopes {
+		_va[_i] = scopes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, user)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*JWTUser, ...string) error); ok {
+		r0 = rf(user, scopes...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IsActionAllowed provides a mock function with given fields: ctx, action, user, ip, resource
+func (_m *MockAuthService) IsActionAllowed(ctx context.Context, action Action, user *JWTUser, ip string, resource policy.Resource) (bool, error) {
+	ret := _m.Called(ctx, action, user, ip, resource)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(Action, string) bool); ok {
-		r0 = rf(action, ip)
+	if rf, ok := ret.Get(0).(func(context.Context, Action, *JWTUser, string, policy.Resource) bool); ok {
+		r0 = rf(ctx, action, user, ip, resource)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(Action, string) error); ok {
-		r1 = rf(action, ip)
+	if rf, ok := ret.Get(1).(func(context.Context, Action, *JWTUser, string, policy.Resource) error); ok {
+		r1 = rf(ctx, action, user, ip, resource)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -2,27 +2,31 @@
 
 package auth
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // MockIPDataProvider is an autogenerated mock type for the IPDataProvider type
 type MockIPDataProvider struct {
 	mock.Mock
 }
 
-// GetRequestLocation provides a mock function with given fields: ip
-func (_m *MockIPDataProvider) GetRequestLocation(ip string) (string, error) {
-	ret := _m.Called(ip)
+// GetRequestLocation provides a mock function with given fields: ctx, ip
+func (_m *MockIPDataProvider) GetRequestLocation(ctx context.Context, ip string) (string, error) {
+	ret := _m.Called(ctx, ip)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(ip)
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, ip)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(ip)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ip)
 	} else {
 		r1 = ret.Error(1)
 	}
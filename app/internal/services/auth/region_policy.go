@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
+// AnyCountry allows or denies an action from every country
+const AnyCountry = `*`
+
+// RegionRule countries the action may be performed from
+type RegionRule struct {
+	// Allow countries allowed to perform the action, AnyCountry allows every country
+	Allow []string `json:"allow"`
+	// Deny countries denied even if they are allowed
+	Deny []string `json:"deny"`
+	// ExemptRoles roles which may perform the action from any country
+	ExemptRoles []string `json:"exempt_roles"`
+	// FailOpen allows the action when the country can't be resolved (e.g. geolocation is unavailable),
+	// otherwise it is denied
+	FailOpen bool `json:"fail_open"`
+}
+
+// RegionPolicy region rules by action name, actions without rule are not allowed
+type RegionPolicy map[string]RegionRule
+
+// DefaultRegionPolicy policy used when none is configured
+func DefaultRegionPolicy() RegionPolicy {
+	return RegionPolicy{
+		ActionCompanyCreate.String(): {
+			Allow: []string{"CY"},
+		},
+		ActionCompanyDelete.String(): {
+			Allow: []string{"CY"},
+		},
+		ActionCompanyUpdate.String(): {
+			Allow:       []string{"CY"},
+			ExemptRoles: []string{RoleAdmin},
+		},
+	}
+}
+
+// ParseRegionPolicy parses JSON policy and validates its action names
+func ParseRegionPolicy(data []byte) (RegionPolicy, error) {
+	var p RegionPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse region policy: %w", err)
+	}
+	for name, rule := range p {
+		if _, ok := parseAction(name); !ok {
+			return nil, fmt.Errorf("unknown action in region policy: %s", name)
+		}
+		rule.Allow = upperAll(rule.Allow)
+		rule.Deny = upperAll(rule.Deny)
+		p[name] = rule
+	}
+	return p, nil
+}
+
+// LoadRegionPolicy loads policy from the file or from the env, default policy is used if none is configured
+func LoadRegionPolicy(conf *config.RegionPolicy) (RegionPolicy, error) {
+	switch {
+	case conf.File != "":
+		data, err := os.ReadFile(conf.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read region policy file: %w", err)
+		}
+		return ParseRegionPolicy(data)
+	case conf.Rules != "":
+		return ParseRegionPolicy([]byte(conf.Rules))
+	}
+	return DefaultRegionPolicy(), nil
+}
+
+// WatchRegionPolicy reloads the policy file when it is modified. Invalid policy is logged
+// and the previous one is kept. Returned function stops watching.
+func (s *service) WatchRegionPolicy(conf *config.RegionPolicy, log *zap.Logger) func() {
+	return watchFile(conf.File, conf.ReloadInterval, log.With(zap.String("region_policy_file", conf.File)), func() error {
+		p, err := LoadRegionPolicy(conf)
+		if err != nil {
+			return err
+		}
+		s.SetRegionPolicy(p)
+		return nil
+	})
+}
+
+// accessPolicy region rules as rules of policy engine
+func (p RegionPolicy) accessPolicy() *policy.Policy {
+	actions := make([]string, 0, len(p))
+	for action := range p {
+		actions = append(actions, action)
+	}
+	// map iteration order is random, sorting keeps reasons of decisions stable
+	sort.Strings(actions)
+
+	res := &policy.Policy{}
+	for _, action := range actions {
+		res.Rules = append(res.Rules, p[action].rules(action)...)
+	}
+	return res
+}
+
+// rules of the action, exemption goes first so exempt users don't need country lookup
+func (r RegionRule) rules(action string) []policy.Rule {
+	var (
+		res    []policy.Rule
+		prefix = `region:` + action
+	)
+	if len(r.ExemptRoles) > 0 {
+		res = append(res, policy.Rule{
+			ID:      prefix + `:exempt`,
+			Effect:  policy.EffectAllow,
+			Actions: []string{action},
+			Conditions: []policy.Condition{
+				{Attr: policy.AttrSubjectRoles, Op: policy.OpIn, Values: r.ExemptRoles},
+			},
+		})
+	}
+	if len(r.Deny) > 0 {
+		var conditions []policy.Condition
+		if len(r.ExemptRoles) > 0 {
+			conditions = append(conditions, policy.Condition{
+				Attr: policy.AttrSubjectRoles, Op: policy.OpNotIn, Values: r.ExemptRoles,
+			})
+		}
+		res = append(res, policy.Rule{
+			ID:      prefix + `:deny`,
+			Effect:  policy.EffectDeny,
+			Actions: []string{action},
+			Conditions: append(conditions, policy.Condition{
+				Attr: policy.AttrRequestCountry, Op: policy.OpIn, Values: r.Deny,
+			}),
+			FailOpen: r.FailOpen,
+		})
+	}
+	if len(r.Allow) > 0 {
+		rule := policy.Rule{
+			ID:       prefix + `:allow`,
+			Effect:   policy.EffectAllow,
+			Actions:  []string{action},
+			FailOpen: r.FailOpen,
+		}
+		if !contains(r.Allow, AnyCountry) {
+			rule.Conditions = []policy.Condition{
+				{Attr: policy.AttrRequestCountry, Op: policy.OpIn, Values: r.Allow},
+			}
+		}
+		res = append(res, rule)
+	}
+	return res
+}
+
+func upperAll(list []string) []string {
+	res := make([]string, len(list))
+	for i, v := range list {
+		res[i] = strings.ToUpper(strings.TrimSpace(v))
+	}
+	return res
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/policy"
+)
+
+func TestParseRegionPolicy(t *testing.T) {
+	p, err := ParseRegionPolicy([]byte(`{
+		"company_create": {"allow": ["cy", "gr"]},
+		"company_update": {"allow": ["*"], "deny": ["RU"], "exempt_roles": ["admin"]}
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, RegionPolicy{
+		`company_create`: {Allow: []string{"CY", "GR"}, Deny: []string{}},
+		`company_update`: {Allow: []string{"*"}, Deny: []string{"RU"}, ExemptRoles: []string{RoleAdmin}},
+	}, p)
+
+	_, err = ParseRegionPolicy([]byte(`{"company_merge": {"allow": ["*"]}}`))
+	assert.EqualError(t, err, `unknown action in region policy: company_merge`)
+
+	_, err = ParseRegionPolicy([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestRegionRule_rules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    RegionRule
+		country string
+		want    bool
+	}{
+		{
+			name:    `Allowed country`,
+			rule:    RegionRule{Allow: []string{"CY", "GR"}},
+			country: "GR",
+			want:    true,
+		},
+		{
+			name:    `Country is not in allowlist`,
+			rule:    RegionRule{Allow: []string{"CY"}},
+			country: "US",
+			want:    false,
+		},
+		{
+			name:    `Any country`,
+			rule:    RegionRule{Allow: []string{AnyCountry}},
+			country: "US",
+			want:    true,
+		},
+		{
+			name:    `Denied country wins over any`,
+			rule:    RegionRule{Allow: []string{AnyCountry}, Deny: []string{"US"}},
+			country: "US",
+			want:    false,
+		},
+		{
+			name:    `Empty rule denies`,
+			country: "CY",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clmock := new(MockIPDataProvider)
+			clmock.On("GetRequestLocation", mock.Anything, `192.200.150.124`).Return(tt.country, nil)
+			p := RegionPolicy{ActionCompanyCreate.String(): tt.rule}.accessPolicy()
+
+			d := policy.NewEngine(p, clmock).Evaluate(context.Background(), policy.Input{
+				Action:  ActionCompanyCreate.String(),
+				Request: policy.Request{IP: `192.200.150.124`},
+			})
+
+			assert.Equal(t, tt.want, d.Allowed)
+		})
+	}
+}
+
+func Test_service_IsActionAllowedConfiguredPolicy(t *testing.T) {
+	clmock := new(MockIPDataProvider)
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.150.124`).Return(`US`, nil)
+	s := NewService(clmock)
+	s.SetRegionPolicy(RegionPolicy{
+		ActionCompanyCreate.String(): {Allow: []string{AnyCountry}},
+		ActionCompanyDelete.String(): {Allow: []string{"CY"}, ExemptRoles: []string{`support`}},
+	})
+
+	got, err := s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyDelete, &JWTUser{ID: 1, Roles: []string{`support`}}, `192.200.150.124`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyUpdate, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.False(t, got)
+
+	clmock.AssertNotCalled(t, "GetRequestLocation", mock.Anything, mock.Anything)
+}
+
+func TestLoadRegionPolicy(t *testing.T) {
+	p, err := LoadRegionPolicy(&config.RegionPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultRegionPolicy(), p)
+
+	p, err = LoadRegionPolicy(&config.RegionPolicy{Rules: `{"company_create": {"allow": ["GR"]}}`})
+	assert.NoError(t, err)
+	assert.Equal(t, RegionPolicy{`company_create`: {Allow: []string{"GR"}, Deny: []string{}}}, p)
+
+	_, err = LoadRegionPolicy(&config.RegionPolicy{File: filepath.Join(t.TempDir(), `missing.json`)})
+	assert.Error(t, err)
+}
+
+func Test_service_WatchRegionPolicy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), `policy.json`)
+	if err := os.WriteFile(file, []byte(`{"company_create": {"allow": ["CY"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.RegionPolicy{File: file, ReloadInterval: 10 * time.Millisecond}
+	clmock := new(MockIPDataProvider)
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.150.124`).Return(`US`, nil)
+	s := NewService(clmock)
+	stop := s.WatchRegionPolicy(conf, zap.NewNop())
+	defer stop()
+	allowedFromUS := func() bool {
+		got, err := s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+		assert.NoError(t, err)
+		return got
+	}
+
+	// invalid policy is ignored
+	if err := os.WriteFile(file, []byte(`{`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, allowedFromUS())
+
+	if err := os.WriteFile(file, []byte(`{"company_create": {"allow": ["*"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Eventually(t, allowedFromUS, time.Second, 10*time.Millisecond)
+}
+
+func Test_service_IsActionAllowedFailOpen(t *testing.T) {
+	clmock := new(MockIPDataProvider)
+	clmock.On("GetRequestLocation", mock.Anything, `192.200.150.124`).Return(``, errors.New(`circuit is open`))
+	s := NewService(clmock)
+	s.SetRegionPolicy(RegionPolicy{
+		ActionCompanyCreate.String(): {Allow: []string{"CY"}, FailOpen: true},
+		ActionCompanyDelete.String(): {Allow: []string{"CY"}},
+	})
+
+	got, err := s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyDelete, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+	assert.Error(t, err)
+	assert.False(t, got)
+
+	// rules of access policy don't fail open with the region rule
+	s.SetAccessPolicy(&policy.Policy{Rules: []policy.Rule{{
+		ID:      `no-us`,
+		Effect:  policy.EffectDeny,
+		Actions: []string{ActionCompanyCreate.String()},
+		Conditions: []policy.Condition{
+			{Attr: policy.AttrRequestCountry, Op: policy.OpIn, Values: []string{`US`}},
+		},
+	}}})
+	got, err = s.IsActionAllowed(context.Background(), ActionCompanyCreate, &JWTUser{ID: 1}, `192.200.150.124`, policy.Resource{})
+	assert.Error(t, err)
+	assert.False(t, got)
+}
@@ -0,0 +1,6 @@
+package auth
+
+const (
+	// RoleAdmin can manage any company of own tenant
+	RoleAdmin = `admin`
+)
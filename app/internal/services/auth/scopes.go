@@ -0,0 +1,63 @@
+package auth
+
+import "errors"
+
+const (
+	ScopeCompaniesRead   = `companies:read`
+	ScopeCompaniesWrite  = `companies:write`
+	ScopeCompaniesDelete = `companies:delete`
+	// ScopeServiceAccountsManage create, rotate and disable service accounts
+	ScopeServiceAccountsManage = `service_accounts:manage`
+)
+
+var ErrForbidden = errors.New(`insufficient permissions`)
+
+// roleScopes scopes granted by role in addition to the scopes of the token
+var roleScopes = map[string][]string{
+	RoleAdmin: {ScopeCompaniesRead, ScopeCompaniesWrite, ScopeCompaniesDelete, ScopeServiceAccountsManage},
+}
+
+// grantedScopes scopes of the token extended with the scopes of its roles,
+// legacy scopes are granted if the token carries neither roles nor scopes
+func grantedScopes(roles, scopes, legacy []string) []string {
+	if len(roles) == 0 && len(scopes) == 0 {
+		scopes = legacy
+	}
+	res := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !contains(res, scope) {
+			res = append(res, scope)
+		}
+	}
+	for _, role := range roles {
+		for _, scope := range roleScopes[role] {
+			if !contains(res, scope) {
+				res = append(res, scope)
+			}
+		}
+	}
+	return res
+}
+
+// IsKnownScope checks if the scope is one of the scopes of the API
+func IsKnownScope(scope string) bool {
+	return contains(roleScopes[RoleAdmin], scope)
+}
+
+// HasScope checks if the user is granted the scope
+func (ju *JWTUser) HasScope(scope string) bool {
+	return contains(ju.Scopes, scope)
+}
+
+// Authorize checks that the user is granted all required scopes
+func (s *service) Authorize(user *JWTUser, scopes ...string) error {
+	if user == nil {
+		return ErrForbidden
+	}
+	for _, scope := range scopes {
+		if !user.HasScope(scope) {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
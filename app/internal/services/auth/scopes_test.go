@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_service_Authorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    *JWTUser
+		scopes  []string
+		wantErr error
+	}{
+		{
+			name:   `All scopes granted`,
+			user:   &JWTUser{ID: 1, Scopes: []string{ScopeCompaniesRead, ScopeCompaniesWrite}},
+			scopes: []string{ScopeCompaniesRead, ScopeCompaniesWrite},
+		},
+		{
+			name:    `Scope is missing`,
+			user:    &JWTUser{ID: 1, Scopes: []string{ScopeCompaniesRead}},
+			scopes:  []string{ScopeCompaniesDelete},
+			wantErr: ErrForbidden,
+		},
+		{
+			name: `No scopes required`,
+			user: &JWTUser{ID: 1},
+		},
+		{
+			name:    `Anonymous user`,
+			scopes:  []string{ScopeCompaniesRead},
+			wantErr: ErrForbidden,
+		},
+	}
+	s := &service{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Authorize(tt.user, tt.scopes...)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func Test_grantedScopes(t *testing.T) {
+	assert.Equal(t, []string{}, grantedScopes(nil, nil, nil))
+	assert.Equal(t, []string{ScopeCompaniesRead}, grantedScopes(nil, nil, []string{ScopeCompaniesRead}))
+	assert.Equal(t, []string{ScopeCompaniesRead}, grantedScopes(nil, []string{ScopeCompaniesRead, ScopeCompaniesRead}, nil))
+	assert.Equal(t, []string{}, grantedScopes([]string{`unknown`}, nil, []string{ScopeCompaniesRead}))
+	assert.Equal(
+		t,
+		[]string{ScopeCompaniesRead, ScopeCompaniesWrite, ScopeCompaniesDelete, ScopeServiceAccountsManage},
+		grantedScopes([]string{RoleAdmin}, []string{ScopeCompaniesRead}, nil),
+	)
+}
+
+func Test_IsKnownScope(t *testing.T) {
+	assert.True(t, IsKnownScope(ScopeCompaniesRead))
+	assert.True(t, IsKnownScope(ScopeServiceAccountsManage))
+	assert.False(t, IsKnownScope(`companies:admin`))
+}
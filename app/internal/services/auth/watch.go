@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// watchFile calls reload when the file is modified. Reload errors are logged
+// and the previous state is kept. Returned function stops watching.
+func watchFile(file string, interval time.Duration, log *zap.Logger, reload func() error) func() {
+	if file == "" || interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	var modTime time.Time
+	if info, err := os.Stat(file); err == nil {
+		modTime = info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(file)
+				if err != nil {
+					log.Error("Failed to check policy file", zap.Error(err))
+					continue
+				}
+				if !info.ModTime().After(modTime) {
+					continue
+				}
+				modTime = info.ModTime()
+				if err := reload(); err != nil {
+					log.Error("Failed to reload policy, previous policy is kept", zap.Error(err))
+					continue
+				}
+				log.Info("Policy reloaded")
+			}
+		}
+	}()
+
+	return func() { close(stop) }
+}
@@ -0,0 +1,73 @@
+package companies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+// CollaboratorRoleEditor collaborator can update and delete the company
+const CollaboratorRoleEditor = `editor`
+
+var (
+	ErrForbidden       = errors.New("access to company denied")
+	ErrCompanyNotFound = errors.New("company not found")
+	ErrInvalidRole     = errors.New("invalid collaborator role")
+	// ErrCompanyCodeExists company with the code already exists in the tenant
+	ErrCompanyCodeExists = postgres.ErrCodeExists
+)
+
+type accessLevel int
+
+const (
+	// accessEdit update or delete the company
+	accessEdit accessLevel = iota + 1
+	// accessManage grant and revoke collaborators
+	accessManage
+)
+
+// checkAccess verifies that the caller is admin, owner of the company or a collaborator allowed to edit it
+func (s *service) checkAccess(ctx context.Context, q postgres.Queriable, compID uint64, level accessLevel) error {
+	caller, ok := identity.FromContext(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+	access, err := q.GetCompanyAccess(ctx, compID, caller.UserID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return ErrCompanyNotFound
+		}
+		return fmt.Errorf("failed to check company access: %w", err)
+	}
+
+	switch {
+	case caller.HasRole(auth.RoleAdmin):
+		return nil
+	case access.OwnerID != 0 && access.OwnerID == caller.UserID:
+		return nil
+	case level == accessEdit && access.Role == CollaboratorRoleEditor:
+		return nil
+	}
+	return ErrForbidden
+}
+
+// GetCompanyOwner owner of the company for policy decisions, 0 if the company has no owner.
+// Read from primary, so a company created just now is found.
+func (s *service) GetCompanyOwner(ctx context.Context, compID uint64) (int64, error) {
+	caller, ok := identity.FromContext(ctx)
+	if !ok {
+		return 0, ErrForbidden
+	}
+	access, err := s.db.Queries.GetCompanyAccess(ctx, compID, caller.UserID)
+	if err != nil {
+		if errors.Is(err, postgres.ErrNotFound) {
+			return 0, ErrCompanyNotFound
+		}
+		return 0, fmt.Errorf("failed to get company owner: %w", err)
+	}
+	return access.OwnerID, nil
+}
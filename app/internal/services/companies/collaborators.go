@@ -0,0 +1,93 @@
+package companies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+func (s *service) GetCollaborators(ctx context.Context, compID uint64) ([]*models.Collaborator, error) {
+	select {
+	case <-ctx.Done():
+		s.log.Debug("Skipping getting collaborators due to ctx cancelled")
+		return nil, ctx.Err()
+	default:
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return nil, err
+		}
+		var res []*models.Collaborator
+		err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+			if err := s.checkAccess(ctx, q, compID, accessEdit); err != nil {
+				return err
+			}
+			collaborators, err := q.GetCollaborators(ctx, compID)
+			if err != nil {
+				return fmt.Errorf("failed to get collaborators: %w", err)
+			}
+			res = make([]*models.Collaborator, len(collaborators))
+			for i, c := range collaborators {
+				res[i] = &models.Collaborator{
+					UserID: c.UserID,
+					Role:   c.Role,
+				}
+			}
+			return nil
+		}, postgres.WithReadOnly(), postgres.WithIsoLevel(pgx.RepeatableRead))
+
+		return res, err
+	}
+}
+
+func (s *service) AddCollaborator(ctx context.Context, compID uint64, userID int64, role string) error {
+	select {
+	case <-ctx.Done():
+		s.log.Debug("Skipping adding collaborator due to ctx cancelled")
+		return ctx.Err()
+	default:
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return err
+		}
+		if role != CollaboratorRoleEditor {
+			return ErrInvalidRole
+		}
+		return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+			if err := s.checkAccess(ctx, q, compID, accessManage); err != nil {
+				return err
+			}
+			if err := q.SetCollaborator(ctx, compID, userID, role); err != nil {
+				return fmt.Errorf("failed to add collaborator: %w", err)
+			}
+			return nil
+		})
+	}
+}
+
+func (s *service) RemoveCollaborator(ctx context.Context, compID uint64, userID int64) error {
+	select {
+	case <-ctx.Done():
+		s.log.Debug("Skipping removing collaborator due to ctx cancelled")
+		return ctx.Err()
+	default:
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return err
+		}
+		return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+			if err := s.checkAccess(ctx, q, compID, accessManage); err != nil {
+				return err
+			}
+			err := q.DeleteCollaborator(ctx, compID, userID)
+			if errors.Is(err, postgres.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to remove collaborator: %w", err)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package companies
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+func newCollaboratorsTestService(t *testing.T) (pgxmock.PgxConnIface, *service) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+	return pgxMock, &service{
+		db:     dbMock,
+		quotas: noQuotas,
+		log:    zap.NewExample(),
+	}
+}
+
+func TestGetCollaboratorsOk(t *testing.T) {
+	pgxMock, s := newCollaboratorsTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	expectCompanyAccess(pgxMock, 1, ``)
+	pgxMock.ExpectQuery("SELECT cc.user_id, cc.role FROM company_collaborators cc JOIN companies c .+").
+		WillReturnRows(pgxMock.NewRows([]string{`user_id`, `role`}).AddRow(int64(2), CollaboratorRoleEditor))
+	pgxMock.ExpectCommit()
+
+	got, err := s.GetCollaborators(testCtx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Collaborator{{UserID: 2, Role: CollaboratorRoleEditor}}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestAddCollaboratorOk(t *testing.T) {
+	pgxMock, s := newCollaboratorsTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	expectCompanyAccess(pgxMock, 1, ``)
+	pgxMock.ExpectExec("INSERT INTO company_collaborators").
+		WithArgs(uint64(1), int64(2), CollaboratorRoleEditor, `test`).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	pgxMock.ExpectCommit()
+
+	err := s.AddCollaborator(testCtx, 1, 2, CollaboratorRoleEditor)
+
+	assert.NoError(t, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestAddCollaboratorByEditor(t *testing.T) {
+	pgxMock, s := newCollaboratorsTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	expectCompanyAccess(pgxMock, 3, CollaboratorRoleEditor)
+	pgxMock.ExpectRollback()
+
+	err := s.AddCollaborator(testCtx, 1, 2, CollaboratorRoleEditor)
+
+	assert.Equal(t, ErrForbidden, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestAddCollaboratorInvalidRole(t *testing.T) {
+	pgxMock, s := newCollaboratorsTestService(t)
+
+	err := s.AddCollaborator(testCtx, 1, 2, `owner`)
+
+	assert.Equal(t, ErrInvalidRole, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestAddCollaboratorUnauthenticated(t *testing.T) {
+	pgxMock, s := newCollaboratorsTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectRollback()
+
+	err := s.AddCollaborator(context.Background(), 1, 2, CollaboratorRoleEditor)
+
+	assert.Equal(t, ErrForbidden, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestRemoveCollaboratorOk(t *testing.T) {
+	pgxMock, s := newCollaboratorsTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	expectCompanyAccess(pgxMock, 0, ``)
+	pgxMock.ExpectExec("DELETE FROM company_collaborators").
+		WithArgs(uint64(1), int64(2), `test`).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	pgxMock.ExpectCommit()
+
+	adminCtx := identity.ToContext(context.Background(), identity.Identity{
+		UserID:   5,
+		TenantID: `test`,
+		Roles:    []string{`admin`},
+	})
+	err := s.RemoveCollaborator(adminCtx, 1, 2)
+
+	assert.NoError(t, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/M-Fisher/companies_api/app/internal/models"
 	"github.com/M-Fisher/companies_api/app/internal/services/auth"
 	"github.com/M-Fisher/companies_api/app/internal/services/events"
+	"github.com/M-Fisher/companies_api/app/internal/services/quotas"
 	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
 )
 
@@ -16,20 +17,33 @@ type CompaniesService interface {
 	DeleteCompany(ctx context.Context, companyID uint64) error
 	GetCompanies(ctx context.Context, params models.Company) ([]*models.Company, error)
 	UpdateCompany(ctx context.Context, compID uint64, company models.Company) (*models.Company, error)
+	GetCompanyOwner(ctx context.Context, compID uint64) (int64, error)
+	ImportCompanies(ctx context.Context, companies []models.Company) (*models.ImportResult, error)
+	GetCollaborators(ctx context.Context, compID uint64) ([]*models.Collaborator, error)
+	AddCollaborator(ctx context.Context, compID uint64, userID int64, role string) error
+	RemoveCollaborator(ctx context.Context, compID uint64, userID int64) error
 }
 
 type service struct {
 	db          *postgres.DB
 	authService auth.AuthService
 	event       events.EventsService
+	quotas      quotas.QuotasService
 	log         *zap.Logger
 }
 
-func NewService(db *postgres.DB, authService auth.AuthService, eventService events.EventsService, log *zap.Logger) *service {
+func NewService(
+	db *postgres.DB,
+	authService auth.AuthService,
+	eventService events.EventsService,
+	quotasService quotas.QuotasService,
+	log *zap.Logger,
+) *service {
 	return &service{
 		db:          db,
 		authService: authService,
 		event:       eventService,
+		quotas:      quotasService,
 		log:         log,
 	}
 }
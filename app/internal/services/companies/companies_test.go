@@ -0,0 +1,23 @@
+package companies
+
+import (
+	"context"
+
+	"github.com/pashagolub/pgxmock"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/services/quotas"
+)
+
+// testCtx context of authenticated user from test tenant
+var testCtx = identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `test`})
+
+// noQuotas quotas which don't limit usage, so they run no queries
+var noQuotas = quotas.NewService(nil, quotas.Plans{}, zap.NewNop())
+
+// expectCompanyAccess expects access check of the company owned by ownerID
+func expectCompanyAccess(pgxMock pgxmock.PgxConnIface, ownerID int64, role string) {
+	pgxMock.ExpectQuery("SELECT .+ FROM companies c LEFT JOIN company_collaborators cc .+").
+		WillReturnRows(pgxMock.NewRows([]string{`owner_id`, `role`}).AddRow(ownerID, role))
+}
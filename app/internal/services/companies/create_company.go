@@ -16,6 +16,9 @@ func (s *service) CreateCompany(ctx context.Context, company models.Company) (ui
 		s.log.Debug("Skipping creating company due to ctx cancelled")
 		return 0, ctx.Err()
 	default:
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return 0, err
+		}
 		var (
 			compID uint64
 			err    error
@@ -25,6 +28,9 @@ func (s *service) CreateCompany(ctx context.Context, company models.Company) (ui
 			if err != nil {
 				return fmt.Errorf("failed to create company: %w", err)
 			}
+			if err := s.quotas.CheckCompanies(ctx, q); err != nil {
+				return err
+			}
 
 			dbCompany, err := q.GetCompanyByID(ctx, compID)
 			if err != nil {
@@ -35,12 +41,12 @@ func (s *service) CreateCompany(ctx context.Context, company models.Company) (ui
 				return fmt.Errorf("failed to marshal company for event: %w", err)
 			}
 
-			err = s.event.SendEvent(ctx, events.EventCompanyCreated, resJSON)
-			if err != nil {
-				return fmt.Errorf("failed to send company create event: %w", err)
-			}
-
-			return err
+			return q.SideEffect(func() error {
+				if err := s.event.SendEvent(ctx, events.EventCompanyCreated, resJSON); err != nil {
+					return fmt.Errorf("failed to send company create event: %w", err)
+				}
+				return nil
+			})
 		})
 
 		return compID, err
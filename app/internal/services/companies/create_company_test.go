@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/pashagolub/pgxmock"
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,7 @@ import (
 	"github.com/M-Fisher/companies_api/app/internal/models"
 	"github.com/M-Fisher/companies_api/app/internal/services/auth"
 	"github.com/M-Fisher/companies_api/app/internal/services/events"
+	"github.com/M-Fisher/companies_api/app/internal/services/quotas"
 	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
 )
 
@@ -24,7 +26,8 @@ func TestCreateCompanyContextCancelled(t *testing.T) {
 	cancel()
 
 	s := &service{
-		log: zap.NewExample(),
+		quotas: noQuotas,
+		log:    zap.NewExample(),
 	}
 	got, err := s.CreateCompany(ctxCancelled, models.Company{})
 
@@ -62,9 +65,10 @@ func TestCreateCompanyOk(t *testing.T) {
 		db:          dbMock,
 		authService: authmocks,
 		event:       evmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.CreateCompany(context.Background(), models.Company{
+	got, err := s.CreateCompany(testCtx, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
@@ -92,9 +96,10 @@ func TestCreateCompanyInsertError(t *testing.T) {
 	s := &service{
 		db:          dbMock,
 		authService: authmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.CreateCompany(context.Background(), models.Company{
+	got, err := s.CreateCompany(testCtx, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
@@ -124,9 +129,10 @@ func TestCreateCompanySelectError(t *testing.T) {
 	s := &service{
 		db:          dbMock,
 		authService: authmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.CreateCompany(context.Background(), models.Company{
+	got, err := s.CreateCompany(testCtx, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
@@ -145,7 +151,7 @@ func TestCreateCompanySendEventErr(t *testing.T) {
 		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(uint64(1)))
 	pgxMock.ExpectQuery("SELECT .+ FROM companies WHERE id = .*").
 		WillReturnRows(pgxMock.NewRows([]string{`id`, `name`, `code`, `country`, `website`, `phone`}).AddRow(uint64(1), `test`, `TST`, ``, ``, ``))
-	pgxMock.ExpectRollback()
+	pgxMock.ExpectCommit()
 
 	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
 	if err != nil {
@@ -165,13 +171,110 @@ func TestCreateCompanySendEventErr(t *testing.T) {
 		db:          dbMock,
 		authService: authmocks,
 		event:       evmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.CreateCompany(context.Background(), models.Company{
+	got, err := s.CreateCompany(testCtx, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
 
-	assert.Equal(t, fmt.Errorf("failed to send company create event: %w", errors.New(`send error`)), err)
+	// the company is committed, failed event doesn't fail the request
+	assert.NoError(t, err)
 	assert.Equal(t, uint64(1), got)
+	evmocks.AssertExpectations(t)
+}
+
+func TestCreateCompanyCodeExists(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectQuery("INSERT INTO companies (.+) VALUES (.+) RETURNING id").
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: `companies_tenant_code_key`})
+	pgxMock.ExpectRollback()
+
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	s := &service{
+		db:     dbMock,
+		quotas: noQuotas,
+		log:    zap.NewExample(),
+	}
+	_, err = s.CreateCompany(testCtx, models.Company{
+		Name: "test",
+		Code: "TST",
+	})
+
+	assert.ErrorIs(t, err, ErrCompanyCodeExists)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestCreateCompanyCompaniesQuotaExceeded(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectQuery("INSERT INTO companies (.+) VALUES (.+) RETURNING id").
+		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(uint64(3)))
+	pgxMock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(`test`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	pgxMock.ExpectQuery("SELECT count\\(\\*\\) FROM companies").WithArgs(`test`).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(int64(3)))
+	pgxMock.ExpectRollback()
+
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	evmocks := new(events.MockEventsService)
+	s := &service{
+		db:     dbMock,
+		event:  evmocks,
+		quotas: quotas.NewService(dbMock, quotas.Plans{Default: &quotas.Quota{Companies: 2}}, zap.NewExample()),
+		log:    zap.NewExample(),
+	}
+	got, err := s.CreateCompany(testCtx, models.Company{
+		Name: "test",
+		Code: "TST",
+	})
+
+	assert.Equal(t, &quotas.ExceededError{Metric: quotas.MetricCompanies, Limit: 2}, err)
+	assert.Equal(t, uint64(3), got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+	evmocks.AssertNotCalled(t, "SendEvent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateCompanyRequestsQuotaExceeded(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	exceeded := &quotas.ExceededError{Metric: quotas.MetricRequests, Limit: 10}
+	quotamocks := new(quotas.MockQuotasService)
+	quotamocks.On("UseRequest", testCtx).Return(exceeded)
+	s := &service{
+		db:     dbMock,
+		quotas: quotamocks,
+		log:    zap.NewExample(),
+	}
+	got, err := s.CreateCompany(testCtx, models.Company{
+		Name: "test",
+		Code: "TST",
+	})
+
+	assert.Equal(t, exceeded, err)
+	assert.Equal(t, uint64(0), got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/M-Fisher/companies_api/app/internal/services/events"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
 )
 
 func (s *service) DeleteCompany(ctx context.Context, companyID uint64) error {
@@ -13,15 +14,24 @@ func (s *service) DeleteCompany(ctx context.Context, companyID uint64) error {
 		s.log.Debug("Skipping deleting company due to ctx cancelled")
 		return ctx.Err()
 	default:
-		err := s.db.Queries.DeleteCompany(ctx, companyID)
-		if err != nil {
-			return fmt.Errorf("failed to delete company: %w", err)
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return err
 		}
-		err = s.event.SendEvent(ctx, events.EventCompanyDeleted, []byte(fmt.Sprintf(`{"id": %d}`, companyID)))
-		if err != nil {
-			return fmt.Errorf("failed to send company delete event: %w", err)
-		}
-
-		return nil
+		return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+			if err := s.checkAccess(ctx, q, companyID, accessEdit); err != nil {
+				return err
+			}
+			err := q.DeleteCompany(ctx, companyID)
+			if err != nil {
+				return fmt.Errorf("failed to delete company: %w", err)
+			}
+			return q.SideEffect(func() error {
+				err := s.event.SendEvent(ctx, events.EventCompanyDeleted, []byte(fmt.Sprintf(`{"id": %d}`, companyID)))
+				if err != nil {
+					return fmt.Errorf("failed to send company delete event: %w", err)
+				}
+				return nil
+			})
+		})
 	}
 }
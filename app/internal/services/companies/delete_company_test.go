@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
 	"github.com/M-Fisher/companies_api/app/internal/services/auth"
 	"github.com/M-Fisher/companies_api/app/internal/services/events"
 	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
@@ -18,65 +22,153 @@ import (
 func Test_service_DeleteCompany(t *testing.T) {
 	ctxCancelled, cancel := context.WithCancel(context.Background())
 	cancel()
+	adminCtx := identity.ToContext(context.Background(), identity.Identity{
+		UserID:   2,
+		TenantID: `test`,
+		Roles:    []string{auth.RoleAdmin},
+	})
 	tests := []struct {
 		name    string
 		ctx     context.Context
 		compID  uint64
+		expect  func(pgxMock pgxmock.PgxConnIface)
 		wantErr error
 	}{
 		{
-			name:    `Delete company DB error`,
-			ctx:     context.Background(),
-			compID:  1,
-			wantErr: fmt.Errorf("failed to delete company: %w", errors.New(`db error`)),
+			name:   `Delete company DB error`,
+			ctx:    testCtx,
+			compID: 1,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 1, ``)
+				pgxMock.ExpectExec("DELETE FROM companies").WillReturnError(errors.New(`db error`))
+				pgxMock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("failed to delete company: %w", fmt.Errorf("query: %w", errors.New(`db error`))),
 		},
 		{
 			name:    `Delete company context canceled`,
 			ctx:     ctxCancelled,
 			compID:  2,
+			expect:  func(pgxMock pgxmock.PgxConnIface) {},
 			wantErr: errors.New(`context canceled`),
 		},
 		{
-			name:    `Delete company OK`,
-			ctx:     context.Background(),
-			compID:  3,
+			name:   `Delete company OK`,
+			ctx:    testCtx,
+			compID: 3,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 1, ``)
+				pgxMock.ExpectExec("DELETE FROM companies").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+				pgxMock.ExpectCommit()
+			},
 			wantErr: nil,
 		},
 		{
-			name:    `Delete company - event sending failed`,
-			ctx:     context.Background(),
-			compID:  4,
-			wantErr: fmt.Errorf("failed to send company delete event: %w", errors.New(`failed to send event`)),
+			name:   `Delete company - event sending failed`,
+			ctx:    testCtx,
+			compID: 4,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 1, ``)
+				pgxMock.ExpectExec("DELETE FROM companies").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+				pgxMock.ExpectCommit()
+			},
+			// the company is deleted, failed event doesn't fail the request
+			wantErr: nil,
+		},
+		{
+			name:   `Delete company by editor`,
+			ctx:    identity.ToContext(context.Background(), identity.Identity{UserID: 5, TenantID: `test`}),
+			compID: 3,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 1, CollaboratorRoleEditor)
+				pgxMock.ExpectExec("DELETE FROM companies").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+				pgxMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+		{
+			name:   `Delete company by admin`,
+			ctx:    adminCtx,
+			compID: 3,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 0, ``)
+				pgxMock.ExpectExec("DELETE FROM companies").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+				pgxMock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+		{
+			name:   `Delete company of another user`,
+			ctx:    identity.ToContext(context.Background(), identity.Identity{UserID: 5, TenantID: `test`}),
+			compID: 1,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 1, ``)
+				pgxMock.ExpectRollback()
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:   `Delete company without owner`,
+			ctx:    testCtx,
+			compID: 1,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				expectCompanyAccess(pgxMock, 0, ``)
+				pgxMock.ExpectRollback()
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:   `Delete company not found`,
+			ctx:    testCtx,
+			compID: 1,
+			expect: func(pgxMock pgxmock.PgxConnIface) {
+				pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+				pgxMock.ExpectQuery("SELECT .+ FROM companies c LEFT JOIN company_collaborators cc .+").
+					WillReturnRows(pgxMock.NewRows([]string{`owner_id`, `role`}))
+				pgxMock.ExpectRollback()
+			},
+			wantErr: ErrCompanyNotFound,
 		},
 	}
 
-	qmocks := new(postgres.MockCompaniesQueries)
-	qmocks.On("DeleteCompany", mock.Anything, uint64(1)).Return(errors.New(`db error`))
-	qmocks.On("DeleteCompany", mock.Anything, uint64(3)).Return(nil)
-	qmocks.On("DeleteCompany", mock.Anything, uint64(4)).Return(nil)
 	authmocks := new(auth.MockAuthService)
 	evmocks := new(events.MockEventsService)
 	evmocks.On("SendEvent", mock.Anything, events.EventCompanyDeleted, []byte(`{"id": 3}`)).Return(nil)
 	evmocks.On("SendEvent", mock.Anything, events.EventCompanyDeleted, []byte(`{"id": 4}`)).Return(errors.New("failed to send event"))
 
-	s := &service{
-		db: &postgres.DB{
-			Queries: qmocks,
-			Log:     zap.NewExample(),
-		},
-		authService: authmocks,
-		event:       evmocks,
-		log:         zap.NewExample(),
-	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := s.DeleteCompany(tt.ctx, tt.compID)
+			pgxMock, err := pgxmock.NewConn()
+			if err != nil {
+				t.Fatalf("Failed to start pgxmock: %v", err)
+			}
+			tt.expect(pgxMock)
+			dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+			if err != nil {
+				t.Fatalf("Failed to init test postgres")
+			}
+			s := &service{
+				db:          dbMock,
+				authService: authmocks,
+				event:       evmocks,
+				quotas:      noQuotas,
+				log:         zap.NewExample(),
+			}
+
+			err = s.DeleteCompany(tt.ctx, tt.compID)
 			if tt.wantErr != nil {
 				assert.Equal(t, tt.wantErr, err)
 			} else {
 				assert.NoError(t, err)
 			}
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
 		})
 	}
 }
@@ -13,7 +13,10 @@ func (s *service) GetCompanies(ctx context.Context, params models.Company) ([]*m
 		s.log.Debug("Skipping getting companies due to ctx cancelled")
 		return nil, ctx.Err()
 	default:
-		companies, err := s.db.Queries.GetCompanies(ctx, makeDBCompanyFromRequest(&params))
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return nil, err
+		}
+		companies, err := s.db.ReadQueries().GetCompanies(ctx, makeDBCompanyFromRequest(&params))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get companies: %w", err)
 		}
@@ -74,7 +74,8 @@ func Test_service_GetCompanies(t *testing.T) {
 			Queries: qmocks,
 			Log:     zap.NewExample(),
 		},
-		log: zap.NewExample(),
+		quotas: noQuotas,
+		log:    zap.NewExample(),
 	}
 
 	for _, tt := range tests {
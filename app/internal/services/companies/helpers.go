@@ -13,6 +13,7 @@ func makeCompanyFromDBResponse(comp *postgres.Company) *models.Company {
 		Country: comp.Country,
 		Website: comp.Website,
 		Phone:   comp.Phone,
+		OwnerID: comp.OwnerID,
 	}
 }
 
@@ -0,0 +1,90 @@
+package companies
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/events"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+// importBatchSize companies count loaded in one transaction, also limits size of the import event
+const importBatchSize = 5000
+
+func (s *service) ImportCompanies(ctx context.Context, companies []models.Company) (*models.ImportResult, error) {
+	res := &models.ImportResult{
+		IDs:       []uint64{},
+		Conflicts: []models.ImportConflict{},
+	}
+	if err := s.quotas.UseRequest(ctx); err != nil {
+		return res, err
+	}
+	if err := s.quotas.UseBulkOperation(ctx); err != nil {
+		return res, err
+	}
+	// every row is counted, so import which would exceed the quota midway isn't started,
+	// batches committed before the quota is exceeded by concurrent requests are returned with the error
+	if err := s.quotas.CheckCompaniesAvailable(ctx, len(companies)); err != nil {
+		return res, err
+	}
+	for offset := 0; offset < len(companies); offset += importBatchSize {
+		select {
+		case <-ctx.Done():
+			s.log.Debug("Skipping importing companies due to ctx cancelled", zap.Int("imported", len(res.IDs)))
+			return res, ctx.Err()
+		default:
+		}
+
+		end := offset + importBatchSize
+		if end > len(companies) {
+			end = len(companies)
+		}
+		batch := make([]postgres.Company, 0, end-offset)
+		for i := offset; i < end; i++ {
+			batch = append(batch, makeDBCompanyFromRequest(&companies[i]))
+		}
+
+		var bulkRes *postgres.BulkResult
+		err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+			var err error
+			bulkRes, err = q.BulkCreateCompanies(ctx, batch)
+			if err != nil {
+				return fmt.Errorf("failed to import companies: %w", err)
+			}
+			if err := s.quotas.CheckCompanies(ctx, q); err != nil {
+				return err
+			}
+			if len(bulkRes.IDs) > 0 {
+				resJSON, err := json.Marshal(map[string][]uint64{"ids": bulkRes.IDs})
+				if err != nil {
+					return fmt.Errorf("failed to marshal imported companies for event: %w", err)
+				}
+				return q.SideEffect(func() error {
+					if err := s.event.SendEvent(ctx, events.EventCompaniesImported, resJSON); err != nil {
+						return fmt.Errorf("failed to send companies import event: %w", err)
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return res, err
+		}
+
+		res.IDs = append(res.IDs, bulkRes.IDs...)
+		for _, c := range bulkRes.Conflicts {
+			res.Conflicts = append(res.Conflicts, models.ImportConflict{
+				Row:    offset + c.Row,
+				Code:   c.Code,
+				Reason: c.Reason,
+			})
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,252 @@
+package companies
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/services/events"
+	"github.com/M-Fisher/companies_api/app/internal/services/quotas"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+func expectBulkLoad(pgxMock pgxmock.PgxConnIface, rows int64) {
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("CREATE TEMP TABLE companies_staging").
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	pgxMock.ExpectCopyFrom(`"companies_staging"`, []string{`row_num`, `name`, `code`, `country`, `website`, `phone`}).
+		WillReturnResult(rows)
+}
+
+func TestImportCompaniesOk(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	expectBulkLoad(pgxMock, 3)
+	pgxMock.ExpectQuery("SELECT .+ FROM companies_staging s WHERE .+").
+		WillReturnRows(pgxMock.NewRows([]string{`row`, `code`, `reason`}).AddRow(2, `TST`, `duplicate`))
+	pgxMock.ExpectQuery("INSERT INTO companies \\(.+, tenant_id, owner_id\\) SELECT .+ FROM companies_staging s .+ ON CONFLICT \\(tenant_id, code\\) .+ DO NOTHING RETURNING id, code").
+		WithArgs(`test`, int64(1)).
+		WillReturnRows(pgxMock.NewRows([]string{`id`, `code`}).AddRow(uint64(10), `TST`).AddRow(uint64(11), `TST2`))
+	pgxMock.ExpectCommit()
+
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	evmocks := new(events.MockEventsService)
+	evmocks.On("SendEvent", mock.Anything, events.EventCompaniesImported, []byte(`{"ids":[10,11]}`)).Return(nil)
+
+	s := &service{
+		db:     dbMock,
+		event:  evmocks,
+		quotas: noQuotas,
+		log:    zap.NewExample(),
+	}
+	got, err := s.ImportCompanies(testCtx, []models.Company{
+		{Name: "test", Code: "TST"},
+		{Name: "test2", Code: "TST2"},
+		{Name: "test3", Code: "TST"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ImportResult{
+		IDs: []uint64{10, 11},
+		Conflicts: []models.ImportConflict{
+			{Row: 2, Code: "TST", Reason: "duplicate"},
+		},
+	}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+	evmocks.AssertExpectations(t)
+}
+
+func TestImportCompaniesSendEventErr(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	expectBulkLoad(pgxMock, 3)
+	pgxMock.ExpectQuery("SELECT .+ FROM companies_staging s WHERE .+").
+		WillReturnRows(pgxMock.NewRows([]string{`row`, `code`, `reason`}).AddRow(2, `TST`, `duplicate`))
+	pgxMock.ExpectQuery("INSERT INTO companies \\(.+, tenant_id, owner_id\\) SELECT .+ FROM companies_staging s .+ ON CONFLICT \\(tenant_id, code\\) .+ DO NOTHING RETURNING id, code").
+		WithArgs(`test`, int64(1)).
+		WillReturnRows(pgxMock.NewRows([]string{`id`, `code`}).AddRow(uint64(10), `TST`).AddRow(uint64(11), `TST2`))
+	pgxMock.ExpectCommit()
+
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	evmocks := new(events.MockEventsService)
+	evmocks.On("SendEvent", mock.Anything, events.EventCompaniesImported, []byte(`{"ids":[10,11]}`)).Return(errors.New(`send error`))
+
+	s := &service{
+		db:     dbMock,
+		event:  evmocks,
+		quotas: noQuotas,
+		log:    zap.NewExample(),
+	}
+	got, err := s.ImportCompanies(testCtx, []models.Company{
+		{Name: "test", Code: "TST"},
+		{Name: "test2", Code: "TST2"},
+		{Name: "test3", Code: "TST"},
+	})
+
+	// the batch is committed, so its companies are reported even though the event failed
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ImportResult{
+		IDs: []uint64{10, 11},
+		Conflicts: []models.ImportConflict{
+			{Row: 2, Code: "TST", Reason: "duplicate"},
+		},
+	}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+	evmocks.AssertExpectations(t)
+}
+
+func TestImportCompaniesCopyError(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("CREATE TEMP TABLE companies_staging").
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	pgxMock.ExpectCopyFrom(`"companies_staging"`, []string{`row_num`, `name`, `code`, `country`, `website`, `phone`}).
+		WillReturnError(errors.New(`copy err`))
+	pgxMock.ExpectRollback()
+
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	s := &service{
+		db:     dbMock,
+		quotas: noQuotas,
+		log:    zap.NewExample(),
+	}
+	got, err := s.ImportCompanies(testCtx, []models.Company{
+		{Name: "test", Code: "TST"},
+	})
+
+	assert.Equal(t, fmt.Errorf("failed to import companies: %w", fmt.Errorf("copy: %w", errors.New(`copy err`))), err)
+	assert.Equal(t, &models.ImportResult{IDs: []uint64{}, Conflicts: []models.ImportConflict{}}, got)
+}
+
+func TestImportCompaniesBulkOperationsQuotaExceeded(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	exceeded := &quotas.ExceededError{Metric: quotas.MetricBulkOperations, Limit: 1}
+	quotamocks := new(quotas.MockQuotasService)
+	quotamocks.On("UseRequest", testCtx).Return(nil)
+	quotamocks.On("UseBulkOperation", testCtx).Return(exceeded)
+	s := &service{
+		db:     dbMock,
+		quotas: quotamocks,
+		log:    zap.NewExample(),
+	}
+	got, err := s.ImportCompanies(testCtx, []models.Company{
+		{Name: "test", Code: "TST"},
+	})
+
+	assert.ErrorIs(t, err, quotas.ErrQuotaExceeded)
+	assert.Equal(t, &models.ImportResult{IDs: []uint64{}, Conflicts: []models.ImportConflict{}}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+	quotamocks.AssertExpectations(t)
+}
+
+func TestImportCompaniesCompaniesQuotaExceeded(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	exceeded := &quotas.ExceededError{Metric: quotas.MetricCompanies, Limit: 10}
+	quotamocks := new(quotas.MockQuotasService)
+	quotamocks.On("UseRequest", testCtx).Return(nil)
+	quotamocks.On("UseBulkOperation", testCtx).Return(nil)
+	quotamocks.On("CheckCompaniesAvailable", testCtx, 2).Return(exceeded)
+	s := &service{
+		db:     dbMock,
+		quotas: quotamocks,
+		log:    zap.NewExample(),
+	}
+	got, err := s.ImportCompanies(testCtx, []models.Company{
+		{Name: "test", Code: "TST"},
+		{Name: "test2", Code: "TST2"},
+	})
+
+	// nothing is imported when the whole import doesn't fit the quota
+	assert.ErrorIs(t, err, quotas.ErrQuotaExceeded)
+	assert.Equal(t, &models.ImportResult{IDs: []uint64{}, Conflicts: []models.ImportConflict{}}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+	quotamocks.AssertExpectations(t)
+}
+
+func TestImportCompaniesCreatedConcurrently(t *testing.T) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	expectBulkLoad(pgxMock, 3)
+	pgxMock.ExpectQuery("SELECT .+ FROM companies_staging s WHERE .+").
+		WillReturnRows(pgxMock.NewRows([]string{`row`, `code`, `reason`}).AddRow(2, `TST`, `duplicate`))
+	// TST2 is committed by another import after the conflicts are selected
+	pgxMock.ExpectQuery("INSERT INTO companies .+ ON CONFLICT").
+		WithArgs(`test`, int64(1)).
+		WillReturnRows(pgxMock.NewRows([]string{`id`, `code`}).AddRow(uint64(10), `TST`))
+	pgxMock.ExpectCommit()
+
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+
+	evmocks := new(events.MockEventsService)
+	evmocks.On("SendEvent", mock.Anything, events.EventCompaniesImported, []byte(`{"ids":[10]}`)).Return(nil)
+
+	s := &service{
+		db:     dbMock,
+		event:  evmocks,
+		quotas: noQuotas,
+		log:    zap.NewExample(),
+	}
+	got, err := s.ImportCompanies(testCtx, []models.Company{
+		{Name: "test", Code: "TST"},
+		{Name: "test2", Code: "TST2"},
+		{Name: "test3", Code: "TST"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.ImportResult{
+		IDs: []uint64{10},
+		Conflicts: []models.ImportConflict{
+			{Row: 1, Code: "TST2", Reason: "exists"},
+			{Row: 2, Code: "TST", Reason: "duplicate"},
+		},
+	}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
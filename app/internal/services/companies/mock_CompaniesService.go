@@ -14,6 +14,20 @@ type MockCompaniesService struct {
 	mock.Mock
 }
 
+// AddCollaborator provides a mock function with given fields: ctx, compID, userID, role
+func (_m *MockCompaniesService) AddCollaborator(ctx context.Context, compID uint64, userID int64, role string) error {
+	ret := _m.Called(ctx, compID, userID, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64, string) error); ok {
+		r0 = rf(ctx, compID, userID, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateCompany provides a mock function with given fields: ctx, company
 func (_m *MockCompaniesService) CreateCompany(ctx context.Context, company models.Company) (uint64, error) {
 	ret := _m.Called(ctx, company)
@@ -49,6 +63,29 @@ func (_m *MockCompaniesService) DeleteCompany(ctx context.Context, companyID uin
 	return r0
 }
 
+// GetCollaborators provides a mock function with given fields: ctx, compID
+func (_m *MockCompaniesService) GetCollaborators(ctx context.Context, compID uint64) ([]*models.Collaborator, error) {
+	ret := _m.Called(ctx, compID)
+
+	var r0 []*models.Collaborator
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*models.Collaborator); ok {
+		r0 = rf(ctx, compID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Collaborator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, compID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCompanies provides a mock function with given fields: ctx, params
 func (_m *MockCompaniesService) GetCompanies(ctx context.Context, params models.Company) ([]*models.Company, error) {
 	ret := _m.Called(ctx, params)
@@ -72,6 +109,64 @@ func (_m *MockCompaniesService) GetCompanies(ctx context.Context, params models.
 	return r0, r1
 }
 
+// GetCompanyOwner provides a mock function with given fields: ctx, compID
+func (_m *MockCompaniesService) GetCompanyOwner(ctx context.Context, compID uint64) (int64, error) {
+	ret := _m.Called(ctx, compID)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) int64); ok {
+		r0 = rf(ctx, compID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, compID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportCompanies provides a mock function with given fields: ctx, _a1
+func (_m *MockCompaniesService) ImportCompanies(ctx context.Context, _a1 []models.Company) (*models.ImportResult, error) {
+	ret := _m.Called(ctx, _a1)
+
+	var r0 *models.ImportResult
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Company) *models.ImportResult); ok {
+		r0 = rf(ctx, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ImportResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Company) error); ok {
+		r1 = rf(ctx, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RemoveCollaborator provides a mock function with given fields: ctx, compID, userID
+func (_m *MockCompaniesService) RemoveCollaborator(ctx context.Context, compID uint64, userID int64) error {
+	ret := _m.Called(ctx, compID, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64) error); ok {
+		r0 = rf(ctx, compID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateCompany provides a mock function with given fields: ctx, compID, company
 func (_m *MockCompaniesService) UpdateCompany(ctx context.Context, compID uint64, company models.Company) (*models.Company, error) {
 	ret := _m.Called(ctx, compID, company)
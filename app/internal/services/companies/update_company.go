@@ -16,8 +16,14 @@ func (s *service) UpdateCompany(ctx context.Context, compID uint64, company mode
 		s.log.Debug("Skipping getting companies due to ctx cancelled")
 		return nil, ctx.Err()
 	default:
+		if err := s.quotas.UseRequest(ctx); err != nil {
+			return nil, err
+		}
 		var res *models.Company
 		err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+			if err := s.checkAccess(ctx, q, compID, accessEdit); err != nil {
+				return err
+			}
 			compID, err := q.UpdateCompany(ctx, compID, makeDBCompanyFromRequest(&company))
 			if err != nil {
 				return fmt.Errorf("failed to update company: %w", err)
@@ -33,15 +39,17 @@ func (s *service) UpdateCompany(ctx context.Context, compID uint64, company mode
 				return fmt.Errorf("failed to marshal company for event: %w", err)
 			}
 
-			err = s.event.SendEvent(ctx, events.EventCompanyUpdated, resJSON)
-			if err != nil {
-				res = nil
-				return fmt.Errorf("failed to send company update event: %w", err)
-			}
-
-			return nil
+			return q.SideEffect(func() error {
+				if err := s.event.SendEvent(ctx, events.EventCompanyUpdated, resJSON); err != nil {
+					return fmt.Errorf("failed to send company update event: %w", err)
+				}
+				return nil
+			})
 		})
+		if err != nil {
+			return nil, err
+		}
 
-		return res, err
+		return res, nil
 	}
 }
@@ -24,7 +24,8 @@ func TestUpdateCompanyContextCancelled(t *testing.T) {
 	cancel()
 
 	s := &service{
-		log: zap.NewExample(),
+		quotas: noQuotas,
+		log:    zap.NewExample(),
 	}
 	got, err := s.UpdateCompany(ctxCancelled, 1, models.Company{})
 
@@ -38,7 +39,8 @@ func TestUpdateCompanyOk(t *testing.T) {
 		t.Fatalf("Failed to start pgxmock: %v", err)
 	}
 	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
-	pgxMock.ExpectQuery("^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 RETURNING id$").WithArgs(`TST`, ``, `test`, ``, ``, uint64(1)).
+	expectCompanyAccess(pgxMock, 1, ``)
+	pgxMock.ExpectQuery("^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 AND tenant_id = \\$7 RETURNING id$").WithArgs(`TST`, ``, `test`, ``, ``, uint64(1), `test`).
 		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(uint64(1)))
 	pgxMock.ExpectQuery("SELECT .+ FROM companies WHERE id = .*").
 		WillReturnRows(pgxMock.NewRows([]string{`id`, `name`, `code`, `country`, `website`, `phone`}).AddRow(uint64(1), `test`, `TST`, ``, ``, ``))
@@ -62,9 +64,10 @@ func TestUpdateCompanyOk(t *testing.T) {
 		db:          dbMock,
 		authService: authmocks,
 		event:       evmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.UpdateCompany(context.Background(), 1, models.Company{
+	got, err := s.UpdateCompany(testCtx, 1, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
@@ -83,8 +86,9 @@ func TestUpdateCompanyInsertError(t *testing.T) {
 		t.Fatalf("Failed to start pgxmock: %v", err)
 	}
 	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
-	pgxMock.ExpectQuery("^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 RETURNING id$").
-		WithArgs(`TST`, ``, `test`, ``, ``, uint64(1)).
+	expectCompanyAccess(pgxMock, 1, ``)
+	pgxMock.ExpectQuery("^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 AND tenant_id = \\$7 RETURNING id$").
+		WithArgs(`TST`, ``, `test`, ``, ``, uint64(1), `test`).
 		WillReturnError(errors.New(`update err`))
 	pgxMock.ExpectRollback()
 
@@ -97,9 +101,10 @@ func TestUpdateCompanyInsertError(t *testing.T) {
 	s := &service{
 		db:          dbMock,
 		authService: authmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.UpdateCompany(context.Background(), 1, models.Company{
+	got, err := s.UpdateCompany(testCtx, 1, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
@@ -114,8 +119,9 @@ func TestUpdateCompanySelectError(t *testing.T) {
 		t.Fatalf("Failed to start pgxmock: %v", err)
 	}
 	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
-	pgxMock.ExpectQuery("^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 RETURNING id$").
-		WithArgs(`TST`, ``, `test`, ``, ``, uint64(1)).
+	expectCompanyAccess(pgxMock, 1, ``)
+	pgxMock.ExpectQuery("^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 AND tenant_id = \\$7 RETURNING id$").
+		WithArgs(`TST`, ``, `test`, ``, ``, uint64(1), `test`).
 		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(uint64(1)))
 	pgxMock.ExpectQuery("SELECT .+ FROM companies WHERE id = .*").
 		WillReturnError(errors.New(`select err`))
@@ -130,9 +136,10 @@ func TestUpdateCompanySelectError(t *testing.T) {
 	s := &service{
 		db:          dbMock,
 		authService: authmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.UpdateCompany(context.Background(), 1, models.Company{
+	got, err := s.UpdateCompany(testCtx, 1, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
@@ -147,10 +154,11 @@ func TestUpdateCompanySendEventErr(t *testing.T) {
 		t.Fatalf("Failed to start pgxmock: %v", err)
 	}
 	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	expectCompanyAccess(pgxMock, 1, ``)
 	pgxMock.ExpectQuery(
-		"^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 RETURNING id$",
+		"^UPDATE companies SET code = \\$1, country = \\$2, name = \\$3, phone = \\$4, website = \\$5 WHERE id = \\$6 AND tenant_id = \\$7 RETURNING id$",
 	).
-		WithArgs(`TST`, ``, `test`, ``, ``, uint64(1)).
+		WithArgs(`TST`, ``, `test`, ``, ``, uint64(1), `test`).
 		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(uint64(1)))
 	pgxMock.ExpectQuery("SELECT .+ FROM companies WHERE id = .*").
 		WillReturnRows(
@@ -159,7 +167,7 @@ func TestUpdateCompanySendEventErr(t *testing.T) {
 			).
 				AddRow(uint64(1), `test`, `TST`, ``, ``, ``),
 		)
-	pgxMock.ExpectRollback()
+	pgxMock.ExpectCommit()
 
 	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
 	if err != nil {
@@ -179,13 +187,16 @@ func TestUpdateCompanySendEventErr(t *testing.T) {
 		db:          dbMock,
 		authService: authmocks,
 		event:       evmocks,
+		quotas:      noQuotas,
 		log:         zap.NewExample(),
 	}
-	got, err := s.UpdateCompany(context.Background(), 1, models.Company{
+	got, err := s.UpdateCompany(testCtx, 1, models.Company{
 		Name: "test",
 		Code: "TST",
 	})
 
-	assert.Equal(t, fmt.Errorf("failed to send company update event: %w", errors.New(`send error`)), err)
-	assert.Equal(t, (*models.Company)(nil), got)
+	// the company is committed, failed event doesn't fail the request
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), got.ID)
+	evmocks.AssertExpectations(t)
 }
@@ -28,10 +28,15 @@ func (k *kafkaProducer) Close() error {
 	return k.conn.Close()
 }
 
-func (k *kafkaProducer) WriteMessage(ctx context.Context, eventName string, data []byte) error {
+func (k *kafkaProducer) WriteMessage(ctx context.Context, eventName string, data []byte, headers map[string]string) error {
+	msgHeaders := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		msgHeaders = append(msgHeaders, kafka.Header{Key: key, Value: []byte(value)})
+	}
 	_, err := k.conn.WriteMessages(kafka.Message{
-		Key:   []byte(eventName),
-		Value: data,
+		Key:     []byte(eventName),
+		Value:   data,
+		Headers: msgHeaders,
 	})
 	return err
 }
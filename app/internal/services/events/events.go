@@ -4,17 +4,27 @@ import (
 	"context"
 
 	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
 )
 
 type EventName string
 
 const (
-	EventCompanyCreated EventName = `company_create`
-	EventCompanyUpdated EventName = `company_update`
-	EventCompanyDeleted EventName = `company_delete`
+	EventCompanyCreated    EventName = `company_create`
+	EventCompanyUpdated    EventName = `company_update`
+	EventCompanyDeleted    EventName = `company_delete`
+	EventCompaniesImported EventName = `company_import`
 )
 
+// HeaderTenantID event header with tenant of the changed companies
+const HeaderTenantID = `tenant_id`
+
 func (s *service) SendEvent(ctx context.Context, event EventName, data []byte) error {
 	s.log.Debug("Sending event", zap.String("event_name", string(event)), zap.String("data", string(data)))
-	return s.producer.WriteMessage(ctx, string(event), data)
+	headers := map[string]string{}
+	if id, ok := identity.FromContext(ctx); ok {
+		headers[HeaderTenantID] = id.TenantID
+	}
+	return s.producer.WriteMessage(ctx, string(event), data, headers)
 }
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+type testProducer struct {
+	Producer
+	headers map[string]string
+}
+
+func (p *testProducer) WriteMessage(ctx context.Context, eventName string, data []byte, headers map[string]string) error {
+	p.headers = headers
+	return nil
+}
+
+func TestSendEventTenantHeader(t *testing.T) {
+	producer := &testProducer{}
+	s := NewService(nil, producer, zap.NewExample())
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	err := s.SendEvent(ctx, EventCompanyDeleted, []byte(`{"id": 1}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{HeaderTenantID: `acme`}, producer.headers)
+}
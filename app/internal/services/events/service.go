@@ -17,7 +17,7 @@ type EventsService interface {
 type Producer interface {
 	Close() error
 	GetStatus() error
-	WriteMessage(ctx context.Context, eventName string, data []byte) error
+	WriteMessage(ctx context.Context, eventName string, data []byte, headers map[string]string) error
 }
 
 type service struct {
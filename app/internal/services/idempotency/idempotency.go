@@ -0,0 +1,174 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/metrics"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+var (
+	// ErrKeyInProgress another request with the key has not completed yet
+	ErrKeyInProgress = errors.New("request with the idempotency key is in progress")
+	// ErrKeyReused the key was used for a request with other method, path or body
+	ErrKeyReused = errors.New("idempotency key was used for another request")
+)
+
+// keyRequests outcomes of requests with idempotency key: claimed, replayed, in_progress, reused
+var keyRequests = metrics.NewCounter("idempotency_requests")
+
+// Request request holding the idempotency key
+type Request struct {
+	Scope string
+	Key   string
+	// Response stored response of the completed request, nil if the request must be executed
+	Response []byte
+
+	lockID string
+}
+
+type IdempotencyService interface {
+	// Begin claims the key for the request, the response is set if the request was completed before
+	Begin(ctx context.Context, scope, key, requestHash string) (*Request, error)
+	// Complete stores response of the request, it is replayed to retries until the key expires
+	Complete(ctx context.Context, req *Request, response []byte) error
+	// Release frees the key of the failed request, so it can be retried
+	Release(ctx context.Context, req *Request) error
+}
+
+// service keeps keys in Postgres, so retries are recognized by every instance
+type service struct {
+	db  *postgres.DB
+	log *zap.Logger
+	ttl time.Duration
+	// lockTimeout after which the key of a request which didn't complete can be taken over by its retry
+	lockTimeout time.Duration
+	now         func() time.Time
+}
+
+func NewService(db *postgres.DB, ttl, lockTimeout time.Duration, log *zap.Logger) *service {
+	return &service{
+		db:          db,
+		log:         log,
+		ttl:         ttl,
+		lockTimeout: lockTimeout,
+		now:         time.Now,
+	}
+}
+
+func (s *service) Begin(ctx context.Context, scope, key, requestHash string) (*Request, error) {
+	lockID, err := newLockID()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	req := &Request{Scope: scope, Key: key, lockID: lockID}
+	var stored *postgres.IdempotencyKey
+	err = s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		claimed, err := q.ClaimIdempotencyKey(ctx, postgres.IdempotencyClaim{
+			Scope:       scope,
+			Key:         key,
+			RequestHash: requestHash,
+			LockID:      lockID,
+			Now:         now,
+			ExpiresAt:   now.Add(s.ttl),
+			StaleBefore: now.Add(-s.lockTimeout),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+		if claimed {
+			return nil
+		}
+		stored, err = q.GetIdempotencyKey(ctx, scope, key)
+		if err != nil {
+			return fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case stored == nil:
+		keyRequests.Add("claimed", 1)
+		return req, nil
+	case stored.RequestHash != requestHash:
+		keyRequests.Add("reused", 1)
+		return nil, ErrKeyReused
+	case stored.Response == nil:
+		keyRequests.Add("in_progress", 1)
+		return nil, ErrKeyInProgress
+	}
+	keyRequests.Add("replayed", 1)
+	req.Response = stored.Response
+	return req, nil
+}
+
+func (s *service) Complete(ctx context.Context, req *Request, response []byte) error {
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.SaveIdempotentResponse(ctx, req.Scope, req.Key, req.lockID, response); err != nil {
+			return fmt.Errorf("failed to save idempotent response: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *service) Release(ctx context.Context, req *Request) error {
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.DeleteIdempotencyKey(ctx, req.Scope, req.Key, req.lockID); err != nil {
+			return fmt.Errorf("failed to release idempotency key: %w", err)
+		}
+		return nil
+	})
+}
+
+// Cleanup deletes expired keys
+func (s *service) Cleanup(ctx context.Context) error {
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.DeleteExpiredIdempotencyKeys(ctx, s.now()); err != nil {
+			return fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+		}
+		return nil
+	})
+}
+
+// Watch cleans up expired keys periodically, returned function stops watching
+func (s *service) Watch(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := s.Cleanup(context.Background()); err != nil {
+					s.log.Error("Failed to clean up idempotency keys", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// newLockID identifies the request holding the key, so a request which lost its key doesn't touch the key of another one
+func newLockID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate lock id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+var testNow = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T) (pgxmock.PgxConnIface, *service) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+	s := NewService(dbMock, 24*time.Hour, time.Minute, zap.NewExample())
+	s.now = func() time.Time { return testNow }
+	return pgxMock, s
+}
+
+func Test_service_Begin(t *testing.T) {
+	tests := []struct {
+		name         string
+		claimed      bool
+		stored       *pgxmock.Rows
+		wantResponse []byte
+		wantErr      error
+	}{
+		{name: `Claimed`, claimed: true},
+		{
+			name:         `Completed request is replayed`,
+			stored:       pgxmock.NewRows([]string{`request_hash`, `response`}).AddRow(`hash`, []byte(`{"company_id":1}`)),
+			wantResponse: []byte(`{"company_id":1}`),
+		},
+		{
+			name:    `Request in progress`,
+			stored:  pgxmock.NewRows([]string{`request_hash`, `response`}).AddRow(`hash`, []byte(nil)),
+			wantErr: ErrKeyInProgress,
+		},
+		{
+			name:    `Key used for another request`,
+			stored:  pgxmock.NewRows([]string{`request_hash`, `response`}).AddRow(`other`, []byte(`{}`)),
+			wantErr: ErrKeyReused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, s := newTestService(t)
+			pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+			claim := pgxMock.ExpectQuery("INSERT INTO idempotency_keys").
+				WithArgs(`acme|user:1`, `k1`, `hash`, pgxmock.AnyArg(), testNow, testNow.Add(24*time.Hour), testNow.Add(-time.Minute))
+			if tt.claimed {
+				claim.WillReturnRows(pgxMock.NewRows([]string{`lock_id`}).AddRow(`lock`))
+			} else {
+				claim.WillReturnRows(pgxMock.NewRows([]string{`lock_id`}))
+				pgxMock.ExpectQuery("SELECT request_hash, response FROM idempotency_keys").
+					WithArgs(`acme|user:1`, `k1`).
+					WillReturnRows(tt.stored)
+			}
+			pgxMock.ExpectCommit()
+
+			got, err := s.Begin(context.Background(), `acme|user:1`, `k1`, `hash`)
+
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.wantResponse, got.Response)
+				assert.NotEmpty(t, got.lockID)
+			}
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_service_CompleteAndRelease(t *testing.T) {
+	req := &Request{Scope: `acme|user:1`, Key: `k1`, lockID: `lock`}
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("UPDATE idempotency_keys SET response").
+		WithArgs(`acme|user:1`, `k1`, `lock`, []byte(`{}`)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	pgxMock.ExpectCommit()
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("DELETE FROM idempotency_keys").
+		WithArgs(`acme|user:1`, `k1`, `lock`).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	pgxMock.ExpectCommit()
+
+	assert.NoError(t, s.Complete(context.Background(), req, []byte(`{}`)))
+	assert.NoError(t, s.Release(context.Background(), req))
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
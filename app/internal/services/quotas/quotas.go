@@ -0,0 +1,340 @@
+package quotas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+// Metrics limited by quotas
+const (
+	MetricRequests       = `requests`
+	MetricBulkOperations = `bulk_operations`
+	MetricCompanies      = `companies`
+)
+
+const (
+	requestsWindow       = 24 * time.Hour
+	bulkOperationsWindow = time.Hour
+	// counterRetention how long counters are kept after their window started
+	counterRetention = 2 * requestsWindow
+
+	subjectTenant = `tenant`
+)
+
+// ErrQuotaExceeded matches every ExceededError
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// ExceededError the caller used up its quota of the metric
+type ExceededError struct {
+	Metric string
+	Limit  int64
+	// ResetAt when the quota is available again, zero if the usage is not reset
+	ResetAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	switch e.Metric {
+	case MetricRequests:
+		return fmt.Sprintf("quota of %d requests per day exceeded, resets at %s", e.Limit, e.ResetAt.Format(time.RFC3339))
+	case MetricBulkOperations:
+		return fmt.Sprintf("quota of %d bulk operations per hour exceeded, resets at %s", e.Limit, e.ResetAt.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("quota of %d stored %s exceeded", e.Limit, e.Metric)
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Quota limits of usage, 0 means unlimited
+type Quota struct {
+	RequestsPerDay        int64 `json:"requests_per_day"`
+	BulkOperationsPerHour int64 `json:"bulk_operations_per_hour"`
+	// Companies limits companies stored by the whole tenant
+	Companies int64 `json:"companies"`
+}
+
+func (q Quota) validate() error {
+	if q.RequestsPerDay < 0 || q.BulkOperationsPerHour < 0 || q.Companies < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// Plans quotas of tenants and API keys. The quota of an API key replaces the quota of its tenant
+// for requests and bulk operations, companies are always limited by the tenant's quota.
+// Tenants without own quota share limits of the default quota, every tenant is counted separately.
+type Plans struct {
+	Default *Quota           `json:"default"`
+	Tenants map[string]Quota `json:"tenants"`
+	APIKeys map[int64]Quota  `json:"api_keys"`
+}
+
+// ParsePlans parses JSON plans and validates their limits
+func ParsePlans(data []byte) (Plans, error) {
+	var p Plans
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Plans{}, fmt.Errorf("failed to parse quota plans: %w", err)
+	}
+	if p.Default != nil {
+		if err := p.Default.validate(); err != nil {
+			return Plans{}, fmt.Errorf("invalid default quota: %w", err)
+		}
+	}
+	for tenant, q := range p.Tenants {
+		if err := q.validate(); err != nil {
+			return Plans{}, fmt.Errorf("invalid quota of tenant %s: %w", tenant, err)
+		}
+	}
+	for id, q := range p.APIKeys {
+		if err := q.validate(); err != nil {
+			return Plans{}, fmt.Errorf("invalid quota of api key %d: %w", id, err)
+		}
+	}
+	return p, nil
+}
+
+// LoadPlans loads plans from the file or from the env, usage is not limited if none are configured
+func LoadPlans(conf *config.Quota) (Plans, error) {
+	switch {
+	case conf.File != "":
+		data, err := os.ReadFile(conf.File)
+		if err != nil {
+			return Plans{}, fmt.Errorf("failed to read quota plans file: %w", err)
+		}
+		return ParsePlans(data)
+	case conf.Plans != "":
+		return ParsePlans([]byte(conf.Plans))
+	}
+	return Plans{}, nil
+}
+
+// tenantQuota quota of the tenant or the default one
+func (p Plans) tenantQuota(tenantID string) Quota {
+	if q, ok := p.Tenants[tenantID]; ok {
+		return q
+	}
+	if p.Default != nil {
+		return *p.Default
+	}
+	return Quota{}
+}
+
+// usageQuota quota limiting requests and bulk operations of the caller and subject it is counted for
+func (p Plans) usageQuota(caller identity.Identity) (Quota, string) {
+	if caller.APIKeyID != 0 {
+		if q, ok := p.APIKeys[caller.APIKeyID]; ok {
+			return q, `api_key:` + strconv.FormatInt(caller.APIKeyID, 10)
+		}
+	}
+	return p.tenantQuota(caller.TenantID), subjectTenant
+}
+
+type QuotasService interface {
+	// UseRequest counts request of the caller
+	UseRequest(ctx context.Context) error
+	// UseBulkOperation counts bulk operation of the caller
+	UseBulkOperation(ctx context.Context) error
+	// CheckCompanies checks companies stored by the caller's tenant, including ones created by the transaction
+	CheckCompanies(ctx context.Context, q *postgres.Queries) error
+	// CheckCompaniesAvailable checks the caller's tenant can store count more companies, they are not reserved,
+	// so CheckCompanies still checks them when they are created
+	CheckCompaniesAvailable(ctx context.Context, count int) error
+	GetUsage(ctx context.Context) (*models.Usage, error)
+}
+
+// service counts usage in Postgres, so quotas are shared by instances.
+// Only usage which is limited is counted.
+type service struct {
+	db    *postgres.DB
+	plans Plans
+	log   *zap.Logger
+	now   func() time.Time
+}
+
+func NewService(db *postgres.DB, plans Plans, log *zap.Logger) *service {
+	return &service{
+		db:    db,
+		plans: plans,
+		log:   log,
+		now:   time.Now,
+	}
+}
+
+func (s *service) UseRequest(ctx context.Context) error {
+	caller, _ := identity.FromContext(ctx)
+	quota, subject := s.plans.usageQuota(caller)
+	return s.use(ctx, subject, MetricRequests, quota.RequestsPerDay, requestsWindow)
+}
+
+func (s *service) UseBulkOperation(ctx context.Context) error {
+	caller, _ := identity.FromContext(ctx)
+	quota, subject := s.plans.usageQuota(caller)
+	return s.use(ctx, subject, MetricBulkOperations, quota.BulkOperationsPerHour, bulkOperationsWindow)
+}
+
+// use increments the counter of the current window, the increment is rolled back if the quota is exceeded
+func (s *service) use(ctx context.Context, subject, metric string, limit int64, window time.Duration) error {
+	if limit == 0 {
+		return nil
+	}
+	start := s.now().UTC().Truncate(window)
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		count, err := q.IncrementUsage(ctx, subject, metric, start, 1)
+		if err != nil {
+			return fmt.Errorf("failed to count %s: %w", metric, err)
+		}
+		if count > limit {
+			return &ExceededError{Metric: metric, Limit: limit, ResetAt: start.Add(window)}
+		}
+		return nil
+	})
+}
+
+func (s *service) CheckCompanies(ctx context.Context, q *postgres.Queries) error {
+	caller, _ := identity.FromContext(ctx)
+	limit := s.plans.tenantQuota(caller.TenantID).Companies
+	if limit == 0 {
+		return nil
+	}
+	// companies created by concurrent transactions are counted once they are committed
+	if err := q.LockCompaniesQuota(ctx); err != nil {
+		return fmt.Errorf("failed to lock companies quota: %w", err)
+	}
+	count, err := q.CountCompanies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count companies: %w", err)
+	}
+	if count > limit {
+		return &ExceededError{Metric: MetricCompanies, Limit: limit}
+	}
+	return nil
+}
+
+func (s *service) CheckCompaniesAvailable(ctx context.Context, count int) error {
+	caller, _ := identity.FromContext(ctx)
+	limit := s.plans.tenantQuota(caller.TenantID).Companies
+	if limit == 0 {
+		return nil
+	}
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		stored, err := q.CountCompanies(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count companies: %w", err)
+		}
+		if stored+int64(count) > limit {
+			return &ExceededError{Metric: MetricCompanies, Limit: limit}
+		}
+		return nil
+	}, postgres.WithReadOnly())
+}
+
+// GetUsage returns usage of the caller in current windows
+func (s *service) GetUsage(ctx context.Context) (*models.Usage, error) {
+	caller, ok := identity.FromContext(ctx)
+	if !ok {
+		return nil, postgres.ErrTenantRequired
+	}
+	quota, subject := s.plans.usageQuota(caller)
+	companiesLimit := s.plans.tenantQuota(caller.TenantID).Companies
+	now := s.now().UTC()
+
+	res := &models.Usage{
+		TenantID:  caller.TenantID,
+		Subject:   subject,
+		Companies: models.UsageCounter{Limit: limitOrNil(companiesLimit)},
+	}
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		var err error
+		res.Requests, err = usageCounter(ctx, q, subject, MetricRequests, quota.RequestsPerDay, now.Truncate(requestsWindow), requestsWindow)
+		if err != nil {
+			return err
+		}
+		res.BulkOperations, err = usageCounter(ctx, q, subject, MetricBulkOperations, quota.BulkOperationsPerHour, now.Truncate(bulkOperationsWindow), bulkOperationsWindow)
+		if err != nil {
+			return err
+		}
+		res.Companies.Used, err = q.CountCompanies(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count companies: %w", err)
+		}
+		return nil
+	}, postgres.WithReadOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// usageCounter returns counter of the window, nil if the metric is not limited
+func usageCounter(
+	ctx context.Context,
+	q *postgres.Queries,
+	subject, metric string,
+	limit int64,
+	start time.Time,
+	window time.Duration,
+) (*models.UsageCounter, error) {
+	if limit == 0 {
+		return nil, nil
+	}
+	used, err := q.GetUsage(ctx, subject, metric, start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s usage: %w", metric, err)
+	}
+	resetAt := start.Add(window)
+	return &models.UsageCounter{Used: used, Limit: &limit, ResetAt: &resetAt}, nil
+}
+
+func limitOrNil(limit int64) *int64 {
+	if limit == 0 {
+		return nil
+	}
+	return &limit
+}
+
+// Cleanup deletes counters of past windows of all tenants
+func (s *service) Cleanup(ctx context.Context) error {
+	return s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.DeleteExpiredUsage(ctx, s.now().Add(-counterRetention)); err != nil {
+			return fmt.Errorf("failed to delete expired usage: %w", err)
+		}
+		return nil
+	})
+}
+
+// Watch cleans up counters periodically, returned function stops watching
+func (s *service) Watch(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := s.Cleanup(context.Background()); err != nil {
+					s.log.Error("Failed to clean up usage counters", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
@@ -0,0 +1,252 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/models"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+var (
+	testNow   = time.Date(2023, 1, 2, 15, 30, 0, 0, time.UTC)
+	testDay   = time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	testHour  = time.Date(2023, 1, 2, 15, 0, 0, 0, time.UTC)
+	testCtx   = identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	testPlans = Plans{
+		Default: &Quota{RequestsPerDay: 100},
+		Tenants: map[string]Quota{
+			`acme`: {RequestsPerDay: 2, BulkOperationsPerHour: 1, Companies: 10},
+		},
+		APIKeys: map[int64]Quota{
+			7: {RequestsPerDay: 5},
+		},
+	}
+)
+
+func newTestService(t *testing.T, plans Plans) (pgxmock.PgxConnIface, *service) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+	s := NewService(dbMock, plans, zap.NewExample())
+	s.now = func() time.Time { return testNow }
+	return pgxMock, s
+}
+
+func TestParsePlans(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Plans
+		wantErr bool
+	}{
+		{
+			name: `Plans`,
+			data: `{"default":{"requests_per_day":100},"tenants":{"acme":{"companies":10}},"api_keys":{"7":{"bulk_operations_per_hour":1}}}`,
+			want: Plans{
+				Default: &Quota{RequestsPerDay: 100},
+				Tenants: map[string]Quota{`acme`: {Companies: 10}},
+				APIKeys: map[int64]Quota{7: {BulkOperationsPerHour: 1}},
+			},
+		},
+		{name: `Negative limit`, data: `{"tenants":{"acme":{"companies":-1}}}`, wantErr: true},
+		{name: `Invalid api key id`, data: `{"api_keys":{"key":{}}}`, wantErr: true},
+		{name: `Invalid JSON`, data: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePlans([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlans_usageQuota(t *testing.T) {
+	tests := []struct {
+		name        string
+		caller      identity.Identity
+		want        Quota
+		wantSubject string
+	}{
+		{
+			name:        `Tenant quota`,
+			caller:      identity.Identity{TenantID: `acme`},
+			want:        Quota{RequestsPerDay: 2, BulkOperationsPerHour: 1, Companies: 10},
+			wantSubject: `tenant`,
+		},
+		{
+			name:        `API key quota`,
+			caller:      identity.Identity{TenantID: `acme`, APIKeyID: 7},
+			want:        Quota{RequestsPerDay: 5},
+			wantSubject: `api_key:7`,
+		},
+		{
+			name:        `API key without quota`,
+			caller:      identity.Identity{TenantID: `acme`, APIKeyID: 8},
+			want:        Quota{RequestsPerDay: 2, BulkOperationsPerHour: 1, Companies: 10},
+			wantSubject: `tenant`,
+		},
+		{
+			name:        `Default quota`,
+			caller:      identity.Identity{TenantID: `other`},
+			want:        Quota{RequestsPerDay: 100},
+			wantSubject: `tenant`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, subject := testPlans.usageQuota(tt.caller)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSubject, subject)
+		})
+	}
+}
+
+func Test_service_UseRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int64
+		wantErr error
+	}{
+		{name: `Within quota`, count: 2},
+		{
+			name:    `Quota exceeded`,
+			count:   3,
+			wantErr: &ExceededError{Metric: MetricRequests, Limit: 2, ResetAt: testDay.Add(24 * time.Hour)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, s := newTestService(t, testPlans)
+			pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+			pgxMock.ExpectQuery("INSERT INTO usage_counters").
+				WithArgs(`acme`, `tenant`, MetricRequests, testDay, int64(1)).
+				WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(tt.count))
+			if tt.wantErr != nil {
+				pgxMock.ExpectRollback()
+			} else {
+				pgxMock.ExpectCommit()
+			}
+
+			err := s.UseRequest(testCtx)
+
+			assert.Equal(t, tt.wantErr, err)
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_service_UseBulkOperationUnlimited(t *testing.T) {
+	pgxMock, s := newTestService(t, testPlans)
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `other`})
+
+	err := s.UseBulkOperation(ctx)
+
+	assert.NoError(t, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func Test_service_CheckCompanies(t *testing.T) {
+	pgxMock, s := newTestService(t, testPlans)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(`acme`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	pgxMock.ExpectQuery("SELECT count\\(\\*\\) FROM companies").WithArgs(`acme`).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(int64(11)))
+	pgxMock.ExpectRollback()
+
+	err := s.db.ExecTx(testCtx, func(q *postgres.Queries) error {
+		return s.CheckCompanies(testCtx, q)
+	})
+
+	assert.True(t, errors.Is(err, ErrQuotaExceeded))
+	assert.EqualError(t, err, `quota of 10 stored companies exceeded`)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func Test_service_CheckCompaniesAvailable(t *testing.T) {
+	tests := []struct {
+		name    string
+		stored  int64
+		wantErr bool
+	}{
+		{name: `Fits the quota`, stored: 7},
+		{name: `Exceeds the quota`, stored: 8, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, s := newTestService(t, testPlans)
+			pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadOnly})
+			pgxMock.ExpectQuery("SELECT count\\(\\*\\) FROM companies").WithArgs(`acme`).
+				WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(tt.stored))
+			if tt.wantErr {
+				pgxMock.ExpectRollback()
+			} else {
+				pgxMock.ExpectCommit()
+			}
+
+			err := s.CheckCompaniesAvailable(testCtx, 3)
+
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrQuotaExceeded), err)
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func Test_service_GetUsage(t *testing.T) {
+	pgxMock, s := newTestService(t, testPlans)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadOnly})
+	pgxMock.ExpectQuery("SELECT count FROM usage_counters").
+		WithArgs(`acme`, `tenant`, MetricRequests, testDay).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(int64(1)))
+	pgxMock.ExpectQuery("SELECT count FROM usage_counters").
+		WithArgs(`acme`, `tenant`, MetricBulkOperations, testHour).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}))
+	pgxMock.ExpectQuery("SELECT count\\(\\*\\) FROM companies").WithArgs(`acme`).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(int64(4)))
+	pgxMock.ExpectCommit()
+
+	got, err := s.GetUsage(testCtx)
+
+	requestsLimit, bulkLimit, companiesLimit := int64(2), int64(1), int64(10)
+	requestsReset, bulkReset := testDay.Add(24*time.Hour), testHour.Add(time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Usage{
+		TenantID:       `acme`,
+		Subject:        `tenant`,
+		Requests:       &models.UsageCounter{Used: 1, Limit: &requestsLimit, ResetAt: &requestsReset},
+		BulkOperations: &models.UsageCounter{Used: 0, Limit: &bulkLimit, ResetAt: &bulkReset},
+		Companies:      models.UsageCounter{Used: 4, Limit: &companiesLimit},
+	}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func Test_service_Cleanup(t *testing.T) {
+	pgxMock, s := newTestService(t, Plans{})
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("DELETE FROM usage_counters").WithArgs(testNow.Add(-48 * time.Hour)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 3))
+	pgxMock.ExpectCommit()
+
+	assert.NoError(t, s.Cleanup(context.Background()))
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
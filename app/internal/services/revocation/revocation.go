@@ -0,0 +1,188 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+type RevocationService interface {
+	IsRevoked(user *auth.JWTUser) bool
+	RevokeToken(ctx context.Context, user *auth.JWTUser) error
+	RevokeUserTokens(ctx context.Context, userID int64, before time.Time) error
+}
+
+// ErrNoTokenID token without jti can be revoked only with all tokens of its user
+var ErrNoTokenID = errors.New("token has no jti")
+
+type userKey struct {
+	tenantID string
+	userID   int64
+}
+
+// service keeps revocations stored in Postgres in memory, so tokens are checked without queries.
+// Revocations are never undone, so the cache only grows until revoked tokens expire.
+type service struct {
+	db  *postgres.DB
+	log *zap.Logger
+	mu  sync.RWMutex
+	// tokens expiry of revoked tokens by jti, zero if the token never expires
+	tokens map[string]time.Time
+	// users tokens of the user issued before the time are revoked
+	users map[userKey]time.Time
+}
+
+func NewService(db *postgres.DB, log *zap.Logger) *service {
+	return &service{
+		db:     db,
+		log:    log,
+		tokens: map[string]time.Time{},
+		users:  map[userKey]time.Time{},
+	}
+}
+
+// IsRevoked checks the token by its jti and by revocation of all tokens of its user,
+// tokens without iat are revoked with all tokens of the user
+func (s *service) IsRevoked(user *auth.JWTUser) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if user.TokenID != "" {
+		if exp, ok := s.tokens[user.TokenID]; ok && (exp.IsZero() || exp.After(time.Now())) {
+			return true
+		}
+	}
+	if before, ok := s.users[userKey{tenantID: user.TenantID, userID: user.ID}]; ok {
+		return user.IssuedAt.IsZero() || user.IssuedAt.Before(before)
+	}
+	return false
+}
+
+// RevokeToken revokes the token until it expires
+func (s *service) RevokeToken(ctx context.Context, user *auth.JWTUser) error {
+	if user.TokenID == "" {
+		return ErrNoTokenID
+	}
+	var expiresAt *time.Time
+	if !user.ExpiresAt.IsZero() {
+		expiresAt = &user.ExpiresAt
+	}
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.RevokeToken(ctx, user.TokenID, user.ID, expiresAt); err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[user.TokenID] = user.ExpiresAt
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeUserTokens revokes tokens of the user from the caller's tenant issued before the time
+func (s *service) RevokeUserTokens(ctx context.Context, userID int64, before time.Time) error {
+	caller, ok := identity.FromContext(ctx)
+	if !ok {
+		return postgres.ErrTenantRequired
+	}
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.RevokeUserTokens(ctx, userID, before); err != nil {
+			return fmt.Errorf("failed to revoke user tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.revokeUser(userKey{tenantID: caller.TenantID, userID: userID}, before)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *service) revokeUser(key userKey, before time.Time) {
+	if before.After(s.users[key]) {
+		s.users[key] = before
+	}
+}
+
+// Refresh loads revocations made by other instances and drops expired tokens
+func (s *service) Refresh(ctx context.Context) error {
+	var (
+		tokens []*postgres.RevokedToken
+		users  []*postgres.UserRevocation
+	)
+	err := s.db.ExecTx(ctx, func(q *postgres.Queries) error {
+		if err := q.DeleteExpiredRevokedTokens(ctx); err != nil {
+			return fmt.Errorf("failed to delete expired tokens: %w", err)
+		}
+		var err error
+		tokens, err = q.GetRevokedTokens(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get revoked tokens: %w", err)
+		}
+		users, err = q.GetUserRevocations(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get user revocations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for jti, exp := range s.tokens {
+		if !exp.IsZero() && !exp.After(now) {
+			delete(s.tokens, jti)
+		}
+	}
+	for _, t := range tokens {
+		var exp time.Time
+		if t.ExpiresAt != nil {
+			exp = *t.ExpiresAt
+		}
+		s.tokens[t.JTI] = exp
+	}
+	for _, u := range users {
+		s.revokeUser(userKey{tenantID: u.TenantID, userID: u.UserID}, u.RevokedBefore)
+	}
+	return nil
+}
+
+// Watch refreshes revocations periodically, returned function stops watching
+func (s *service) Watch(interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := s.Refresh(context.Background()); err != nil {
+					s.log.Error("Failed to refresh token revocations", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
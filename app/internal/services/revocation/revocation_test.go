@@ -0,0 +1,129 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+	"github.com/M-Fisher/companies_api/app/internal/services/auth"
+	"github.com/M-Fisher/companies_api/app/internal/storage/postgres"
+)
+
+var testCtx = identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `test`})
+
+func newTestService(t *testing.T) (pgxmock.PgxConnIface, *service) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	dbMock, err := postgres.NewTestPostgres(pgxMock, &config.DB{}, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+	return pgxMock, NewService(dbMock, zap.NewExample())
+}
+
+func Test_service_IsRevoked(t *testing.T) {
+	now := time.Now()
+	s := &service{
+		tokens: map[string]time.Time{
+			`revoked`:  now.Add(time.Hour),
+			`forever`:  {},
+			`outdated`: now.Add(-time.Hour),
+		},
+		users: map[userKey]time.Time{
+			{tenantID: `test`, userID: 2}: now.Add(-time.Hour),
+		},
+	}
+	tests := []struct {
+		name string
+		user *auth.JWTUser
+		want bool
+	}{
+		{name: `Revoked token`, user: &auth.JWTUser{ID: 1, TenantID: `test`, TokenID: `revoked`}, want: true},
+		{name: `Revoked token without expiry`, user: &auth.JWTUser{ID: 1, TenantID: `test`, TokenID: `forever`}, want: true},
+		{name: `Expired revocation`, user: &auth.JWTUser{ID: 1, TenantID: `test`, TokenID: `outdated`}},
+		{name: `Other token`, user: &auth.JWTUser{ID: 1, TenantID: `test`, TokenID: `other`}},
+		{name: `Issued before user revocation`, user: &auth.JWTUser{ID: 2, TenantID: `test`, IssuedAt: now.Add(-2 * time.Hour)}, want: true},
+		{name: `Issued after user revocation`, user: &auth.JWTUser{ID: 2, TenantID: `test`, IssuedAt: now}},
+		{name: `Without iat after user revocation`, user: &auth.JWTUser{ID: 2, TenantID: `test`}, want: true},
+		{name: `Same user of other tenant`, user: &auth.JWTUser{ID: 2, TenantID: `acme`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.IsRevoked(tt.user))
+		})
+	}
+}
+
+func Test_service_RevokeToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("INSERT INTO revoked_tokens").
+		WithArgs(`t1`, `test`, int64(1), &exp).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	pgxMock.ExpectCommit()
+
+	user := &auth.JWTUser{ID: 1, TenantID: `test`, TokenID: `t1`, ExpiresAt: exp}
+	err := s.RevokeToken(testCtx, user)
+
+	assert.NoError(t, err)
+	assert.True(t, s.IsRevoked(user))
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func Test_service_RevokeTokenWithoutID(t *testing.T) {
+	pgxMock, s := newTestService(t)
+
+	err := s.RevokeToken(testCtx, &auth.JWTUser{ID: 1, TenantID: `test`})
+
+	assert.ErrorIs(t, err, ErrNoTokenID)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func Test_service_RevokeUserTokens(t *testing.T) {
+	before := time.Now()
+	pgxMock, s := newTestService(t)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("INSERT INTO user_token_revocations").
+		WithArgs(`test`, int64(2), before).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	pgxMock.ExpectCommit()
+
+	err := s.RevokeUserTokens(testCtx, 2, before)
+
+	assert.NoError(t, err)
+	assert.True(t, s.IsRevoked(&auth.JWTUser{ID: 2, TenantID: `test`, IssuedAt: before.Add(-time.Second)}))
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func Test_service_Refresh(t *testing.T) {
+	now := time.Now()
+	exp := now.Add(time.Hour)
+	pgxMock, s := newTestService(t)
+	s.tokens[`local`] = exp
+	s.tokens[`expired`] = now.Add(-time.Minute)
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("DELETE FROM revoked_tokens WHERE expires_at < now\\(\\)").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	pgxMock.ExpectQuery("SELECT jti, expires_at FROM revoked_tokens").
+		WillReturnRows(pgxMock.NewRows([]string{`jti`, `expires_at`}).AddRow(`remote`, &exp).AddRow(`forever`, nil))
+	pgxMock.ExpectQuery("SELECT tenant_id, user_id, revoked_before FROM user_token_revocations").
+		WillReturnRows(pgxMock.NewRows([]string{`tenant_id`, `user_id`, `revoked_before`}).AddRow(`acme`, int64(3), now))
+	pgxMock.ExpectCommit()
+
+	err := s.Refresh(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{`local`: exp, `remote`: exp, `forever`: {}}, s.tokens)
+	assert.Equal(t, map[userKey]time.Time{{tenantID: `acme`, userID: 3}: now}, s.users)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+// touchAPIKeySQL updates last usage of the key at most once a minute, so every request doesn't write to DB
+const touchAPIKeySQL = `UPDATE api_keys SET last_used_at = now()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+
+type APIKeysQueries interface {
+	CreateAPIKey(ctx context.Context, data APIKey) (int64, error)
+	GetAPIKeys(ctx context.Context, userID int64) ([]*APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64) error
+	RevokeAPIKey(ctx context.Context, id int64, userID *int64) error
+}
+
+type APIKey struct {
+	ID         int64
+	TenantID   string
+	UserID     int64
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+var apiKeyColumns = []string{
+	`id`,
+	`tenant_id`,
+	`user_id`,
+	`name`,
+	`prefix`,
+	`key_hash`,
+	`scopes`,
+	`created_at`,
+	`last_used_at`,
+	`revoked_at`,
+}
+
+// CreateAPIKey creates key of the caller
+func (q *Queries) CreateAPIKey(
+	ctx context.Context,
+	data APIKey,
+) (int64, error) {
+	ctx, cancel := q.queryContext(ctx, `CreateAPIKey`, queryWrite)
+	defer cancel()
+	caller, err := callerFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	builder := q.builder.
+		Insert("api_keys").
+		SetMap(
+			map[string]any{
+				`tenant_id`: caller.TenantID,
+				`user_id`:   caller.UserID,
+				`name`:      data.Name,
+				`prefix`:    data.Prefix,
+				`key_hash`:  data.KeyHash,
+				`scopes`:    data.Scopes,
+			},
+		).
+		Suffix(` RETURNING id`)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("build query: %w", err)
+	}
+	var id int64
+	err = q.tx.QueryRow(ctx, query, args...).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetAPIKeys returns keys of the user from the caller's tenant
+func (q *Queries) GetAPIKeys(ctx context.Context, userID int64) ([]*APIKey, error) {
+	ctx, cancel := q.queryContext(ctx, `GetAPIKeys`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*APIKey{}
+	builder := q.builder.
+		Select(apiKeyColumns...).
+		From("api_keys").
+		Where(sq.Eq{`tenant_id`: tenantID, `user_id`: userID}).
+		OrderBy(`id`)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	err = pgxscan.Select(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetAPIKeyByPrefix finds key of any tenant, it is used to authenticate the request
+func (q *Queries) GetAPIKeyByPrefix(
+	ctx context.Context,
+	prefix string,
+) (*APIKey, error) {
+	ctx, cancel := q.queryContext(ctx, `GetAPIKeyByPrefix`, queryRead)
+	defer cancel()
+
+	builder := q.builder.
+		Select(apiKeyColumns...).
+		From("api_keys").
+		Where(sq.Eq{`prefix`: prefix})
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	var res APIKey
+	err = pgxscan.Get(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return &res, nil
+}
+
+func (q *Queries) TouchAPIKey(ctx context.Context, id int64) error {
+	ctx, cancel := q.queryContext(ctx, `TouchAPIKey`, queryWrite)
+	defer cancel()
+
+	_, err := q.tx.Exec(ctx, touchAPIKeySQL, id)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAPIKey revokes key of the caller's tenant, userID limits it to the keys of the user, nil revokes key of any user
+func (q *Queries) RevokeAPIKey(ctx context.Context, id int64, userID *int64) error {
+	ctx, cancel := q.queryContext(ctx, `RevokeAPIKey`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	where := sq.And{
+		sq.Eq{`id`: id, `tenant_id`: tenantID, `revoked_at`: nil},
+	}
+	if userID != nil {
+		where = append(where, sq.Eq{`user_id`: *userID})
+	}
+	builder := q.builder.
+		Update("api_keys").
+		Set(`revoked_at`, sq.Expr(`now()`)).
+		Where(where)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("build query: %w", err)
+	}
+
+	tag, err := q.tx.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
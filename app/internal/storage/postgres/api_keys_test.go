@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+func TestQueries_CreateAPIKey(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("^INSERT INTO api_keys \\(key_hash,name,prefix,scopes,tenant_id,user_id\\) VALUES \\(\\$1,\\$2,\\$3,\\$4,\\$5,\\$6\\) RETURNING id$").
+		WithArgs(`hash`, `ci`, `ak_0123`, []string{`companies:read`}, `acme`, int64(5)).
+		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(int64(2)))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 5, TenantID: `acme`})
+	id, err := db.Queries.(*Queries).CreateAPIKey(ctx, APIKey{
+		Name:    `ci`,
+		Prefix:  `ak_0123`,
+		KeyHash: `hash`,
+		Scopes:  []string{`companies:read`},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_RevokeAPIKey(t *testing.T) {
+	userID := int64(5)
+	tests := []struct {
+		name   string
+		userID *int64
+		query  string
+		args   []interface{}
+	}{
+		{
+			name:   `Own key`,
+			userID: &userID,
+			query:  "^UPDATE api_keys SET revoked_at = now\\(\\) WHERE \\(id = \\$1 AND revoked_at IS NULL AND tenant_id = \\$2 AND user_id = \\$3\\)$",
+			args:   []interface{}{int64(2), `acme`, int64(5)},
+		},
+		{
+			name:   `Own key of service account`,
+			userID: new(int64),
+			query:  "^UPDATE api_keys SET revoked_at = now\\(\\) WHERE \\(id = \\$1 AND revoked_at IS NULL AND tenant_id = \\$2 AND user_id = \\$3\\)$",
+			args:   []interface{}{int64(2), `acme`, int64(0)},
+		},
+		{
+			name:  `Any key of tenant`,
+			query: "^UPDATE api_keys SET revoked_at = now\\(\\) WHERE \\(id = \\$1 AND revoked_at IS NULL AND tenant_id = \\$2\\)$",
+			args:  []interface{}{int64(2), `acme`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, db := newTestDB(t, &config.DB{})
+			pgxMock.ExpectExec(tt.query).
+				WithArgs(tt.args...).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+			ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 5, TenantID: `acme`})
+			err := db.Queries.(*Queries).RevokeAPIKey(ctx, 2, tt.userID)
+
+			assert.NoError(t, err)
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestQueries_RevokeAPIKeyNotFound(t *testing.T) {
+	userID := int64(5)
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectExec("UPDATE api_keys").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 5, TenantID: `acme`})
+	err := db.Queries.(*Queries).RevokeAPIKey(ctx, 2, &userID)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
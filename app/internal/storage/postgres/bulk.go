@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	ConflictCodeExists    = `exists`
+	ConflictCodeDuplicate = `duplicate`
+)
+
+var ErrTxRequired = errors.New("query must be executed in transaction")
+
+type BulkResult struct {
+	IDs       []uint64
+	Conflicts []BulkConflict
+}
+
+// BulkConflict row which was not inserted because a company with the same code
+// already exists in the tenant or appears earlier in the same batch
+type BulkConflict struct {
+	Row    int
+	Code   string
+	Reason string
+}
+
+const companiesStagingTable = `companies_staging`
+
+var companiesStagingColumns = []string{`row_num`, `name`, `code`, `country`, `website`, `phone`}
+
+const (
+	createStagingSQL = `CREATE TEMP TABLE ` + companiesStagingTable + ` (
+		row_num integer NOT NULL,
+		name    TEXT,
+		code    TEXT,
+		country TEXT,
+		website TEXT,
+		phone   TEXT
+	) ON COMMIT DROP`
+
+	// conflictCondition staged rows which must not be merged into companies of tenant $1
+	conflictCondition = `s.code <> '' AND (
+		EXISTS (SELECT 1 FROM companies c WHERE c.tenant_id = $1 AND c.code = s.code)
+		OR EXISTS (SELECT 1 FROM ` + companiesStagingTable + ` d WHERE d.code = s.code AND d.row_num < s.row_num)
+	)`
+
+	selectConflictsSQL = `SELECT s.row_num AS row, s.code,
+		CASE WHEN EXISTS (SELECT 1 FROM companies c WHERE c.tenant_id = $1 AND c.code = s.code)
+			THEN '` + ConflictCodeExists + `' ELSE '` + ConflictCodeDuplicate + `' END AS reason
+		FROM ` + companiesStagingTable + ` s
+		WHERE ` + conflictCondition + `
+		ORDER BY s.row_num`
+
+	// mergeStagingSQL merges staged rows into companies of tenant $1 owned by user $2,
+	// rows whose code was inserted by a concurrent transaction since the conflicts were selected are skipped
+	mergeStagingSQL = `INSERT INTO companies (name, code, country, website, phone, tenant_id, owner_id)
+		SELECT s.name, s.code, s.country, s.website, s.phone, $1, $2
+		FROM ` + companiesStagingTable + ` s
+		WHERE NOT (` + conflictCondition + `)
+		ORDER BY s.row_num
+		ON CONFLICT (tenant_id, code) WHERE code <> '' DO NOTHING
+		RETURNING id, code`
+)
+
+type mergedCompany struct {
+	ID   uint64
+	Code string
+}
+
+// BulkCreateCompanies loads companies into a staging table using COPY protocol and merges
+// them into companies skipping conflicting codes. Must be executed in ExecTx.
+func (q *Queries) BulkCreateCompanies(
+	ctx context.Context,
+	data []Company,
+) (*BulkResult, error) {
+	if q.state == nil {
+		return nil, ErrTxRequired
+	}
+	ctx, cancel := q.queryContext(ctx, `BulkCreateCompanies`, queryBulk)
+	defer cancel()
+	caller, err := callerFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.timeouts.bulk > 0 {
+		_, err = q.tx.Exec(ctx, fmt.Sprintf(`SET LOCAL statement_timeout = %d`, q.timeouts.bulk.Milliseconds()))
+		if err != nil {
+			return nil, fmt.Errorf("set statement timeout: %w", err)
+		}
+	}
+
+	_, err = q.tx.Exec(ctx, createStagingSQL)
+	if err != nil {
+		return nil, fmt.Errorf("create staging table: %w", err)
+	}
+
+	_, err = q.tx.CopyFrom(
+		ctx,
+		pgx.Identifier{companiesStagingTable},
+		companiesStagingColumns,
+		pgx.CopyFromSlice(len(data), func(i int) ([]interface{}, error) {
+			c := data[i]
+			return []interface{}{i, c.Name, c.Code, c.Country, c.Website, c.Phone}, nil
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("copy: %w", err)
+	}
+
+	res := &BulkResult{
+		IDs:       []uint64{},
+		Conflicts: []BulkConflict{},
+	}
+	err = pgxscan.Select(ctx, q.tx, &res.Conflicts, selectConflictsSQL, caller.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("select conflicts: %w", err)
+	}
+
+	var merged []mergedCompany
+	err = pgxscan.Select(ctx, q.tx, &merged, mergeStagingSQL, caller.TenantID, caller.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("merge: %w", err)
+	}
+	for _, c := range merged {
+		res.IDs = append(res.IDs, c.ID)
+	}
+	if len(merged)+len(res.Conflicts) < len(data) {
+		res.Conflicts = appendSkippedConflicts(res.Conflicts, data, merged)
+	}
+
+	return res, nil
+}
+
+// appendSkippedConflicts reports rows which were neither conflicts nor merged: their codes were created
+// concurrently, so they exist now
+func appendSkippedConflicts(conflicts []BulkConflict, data []Company, merged []mergedCompany) []BulkConflict {
+	handled := make(map[int]bool, len(conflicts))
+	for _, c := range conflicts {
+		handled[c.Row] = true
+	}
+	mergedCodes := make(map[string]bool, len(merged))
+	for _, c := range merged {
+		mergedCodes[c.Code] = true
+	}
+	for i, c := range data {
+		if handled[i] || c.Code == "" || mergedCodes[c.Code] {
+			continue
+		}
+		conflicts = append(conflicts, BulkConflict{Row: i, Code: c.Code, Reason: ConflictCodeExists})
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Row < conflicts[j].Row })
+	return conflicts
+}
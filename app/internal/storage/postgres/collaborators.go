@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+var ErrNotFound = errors.New("not found")
+
+const (
+	// setCollaboratorSQL inserts collaborator only if the company belongs to the tenant
+	setCollaboratorSQL = `INSERT INTO company_collaborators (company_id, user_id, role)
+		SELECT id, $2, $3 FROM companies WHERE id = $1 AND tenant_id = $4
+		ON CONFLICT (company_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+
+	deleteCollaboratorSQL = `DELETE FROM company_collaborators cc
+		USING companies c
+		WHERE c.id = cc.company_id AND cc.company_id = $1 AND cc.user_id = $2 AND c.tenant_id = $3`
+)
+
+type Collaborator struct {
+	UserID int64
+	Role   string
+}
+
+// CompanyAccess relation of the user to the company
+type CompanyAccess struct {
+	OwnerID int64
+	// Role collaborator role of the user, empty if the user is not a collaborator
+	Role string
+}
+
+func (q *Queries) GetCompanyAccess(
+	ctx context.Context,
+	compID uint64,
+	userID int64,
+) (*CompanyAccess, error) {
+	ctx, cancel := q.queryContext(ctx, `GetCompanyAccess`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := q.builder.
+		Select(
+			`COALESCE(c.owner_id, 0) AS owner_id`,
+			`COALESCE(cc.role, '') AS role`,
+		).
+		From("companies c").
+		LeftJoin("company_collaborators cc ON cc.company_id = c.id AND cc.user_id = ?", userID).
+		Where(sq.Eq{`c.id`: compID, `c.tenant_id`: tenantID})
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	var res CompanyAccess
+	err = pgxscan.Get(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return &res, nil
+}
+
+func (q *Queries) GetCollaborators(
+	ctx context.Context,
+	compID uint64,
+) ([]*Collaborator, error) {
+	ctx, cancel := q.queryContext(ctx, `GetCollaborators`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*Collaborator{}
+	builder := q.builder.
+		Select(
+			`cc.user_id`,
+			`cc.role`,
+		).
+		From("company_collaborators cc").
+		Join("companies c ON c.id = cc.company_id").
+		Where(sq.Eq{`cc.company_id`: compID, `c.tenant_id`: tenantID}).
+		OrderBy(`cc.user_id`)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	err = pgxscan.Select(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return res, nil
+}
+
+// SetCollaborator grants role on the company to the user or changes the role of existing collaborator
+func (q *Queries) SetCollaborator(
+	ctx context.Context,
+	compID uint64,
+	userID int64,
+	role string,
+) error {
+	ctx, cancel := q.queryContext(ctx, `SetCollaborator`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := q.tx.Exec(ctx, setCollaboratorSQL, compID, userID, role, tenantID)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (q *Queries) DeleteCollaborator(
+	ctx context.Context,
+	compID uint64,
+	userID int64,
+) error {
+	ctx, cancel := q.queryContext(ctx, `DeleteCollaborator`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	tag, err := q.tx.Exec(ctx, deleteCollaboratorSQL, compID, userID, tenantID)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
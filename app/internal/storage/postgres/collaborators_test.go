@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+func TestQueries_GetCompanyAccess(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("^SELECT .+ FROM companies c LEFT JOIN company_collaborators cc ON cc.company_id = c.id AND cc.user_id = \\$1 WHERE c.id = \\$2 AND c.tenant_id = \\$3$").
+		WithArgs(int64(2), uint64(1), `acme`).
+		WillReturnRows(pgxMock.NewRows([]string{`owner_id`, `role`}).AddRow(int64(1), `editor`))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 2, TenantID: `acme`})
+	got, err := db.Queries.GetCompanyAccess(ctx, 1, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &CompanyAccess{OwnerID: 1, Role: `editor`}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_GetCompanyAccessNotFound(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("SELECT .+ FROM companies c").
+		WillReturnRows(pgxMock.NewRows([]string{`owner_id`, `role`}))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 2, TenantID: `acme`})
+	_, err := db.Queries.GetCompanyAccess(ctx, 1, 2)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQueries_SetCollaboratorOtherTenant(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectExec("INSERT INTO company_collaborators").
+		WithArgs(uint64(1), int64(2), `editor`, `acme`).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	err := db.Queries.SetCollaborator(ctx, 1, 2, `editor`)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
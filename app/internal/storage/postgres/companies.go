@@ -2,18 +2,40 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgconn"
 )
 
+// ErrCodeExists company with the code already exists in the tenant
+var ErrCodeExists = errors.New("company code already exists")
+
+// companiesCodeKey unique index of codes within the tenant, empty codes are not unique
+const companiesCodeKey = `companies_tenant_code_key`
+
+// codeError maps violation of unique code to ErrCodeExists
+func codeError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == companiesCodeKey {
+		return ErrCodeExists
+	}
+	return err
+}
+
 type CompaniesQueries interface {
 	CreateCompany(ctx context.Context, params Company) (uint64, error)
 	DeleteCompany(ctx context.Context, compID uint64) error
 	GetCompanies(ctx context.Context, params Company) ([]*Company, error)
 	GetCompanyByID(ctx context.Context, compID uint64) (*Company, error)
 	UpdateCompany(ctx context.Context, compID uint64, data Company) (uint64, error)
+	BulkCreateCompanies(ctx context.Context, data []Company) (*BulkResult, error)
+	GetCompanyAccess(ctx context.Context, compID uint64, userID int64) (*CompanyAccess, error)
+	GetCollaborators(ctx context.Context, compID uint64) ([]*Collaborator, error)
+	SetCollaborator(ctx context.Context, compID uint64, userID int64, role string) error
+	DeleteCollaborator(ctx context.Context, compID uint64, userID int64) error
 }
 
 type Company struct {
@@ -23,21 +45,31 @@ type Company struct {
 	Country string
 	Website string
 	Phone   string
+	OwnerID int64
 }
 
 func (q *Queries) CreateCompany(
 	ctx context.Context,
 	data Company,
 ) (uint64, error) {
+	ctx, cancel := q.queryContext(ctx, `CreateCompany`, queryWrite)
+	defer cancel()
+	caller, err := callerFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	builder := q.builder.
 		Insert("companies").
 		SetMap(
 			map[string]any{
-				`name`:    data.Name,
-				`code`:    data.Code,
-				`country`: data.Country,
-				`website`: data.Website,
-				`phone`:   data.Phone,
+				`name`:      data.Name,
+				`code`:      data.Code,
+				`country`:   data.Country,
+				`website`:   data.Website,
+				`phone`:     data.Phone,
+				`tenant_id`: caller.TenantID,
+				`owner_id`:  caller.UserID,
 			},
 		).
 		Suffix(` RETURNING id`)
@@ -49,7 +81,7 @@ func (q *Queries) CreateCompany(
 	var id uint64
 	err = q.tx.QueryRow(ctx, query, args...).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("query: %w", err)
+		return 0, fmt.Errorf("query: %w", codeError(err))
 	}
 
 	return id, nil
@@ -59,6 +91,13 @@ func (q *Queries) GetCompanies(
 	ctx context.Context,
 	params Company,
 ) ([]*Company, error) {
+	ctx, cancel := q.queryContext(ctx, `GetCompanies`, querySearch)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	res := []*Company{}
 	builder := q.builder.
 		Select(
@@ -68,8 +107,10 @@ func (q *Queries) GetCompanies(
 			`country`,
 			`website`,
 			`phone`,
+			`COALESCE(owner_id, 0) AS owner_id`,
 		).
-		From("companies")
+		From("companies").
+		Where(sq.Eq{`tenant_id`: tenantID})
 	builder = makeGetWheres(builder, params)
 
 	query, args, err := builder.ToSql()
@@ -109,6 +150,13 @@ func (q *Queries) UpdateCompany(
 	compID uint64,
 	data Company,
 ) (uint64, error) {
+	ctx, cancel := q.queryContext(ctx, `UpdateCompany`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	builder := q.builder.
 		Update("companies").
 		SetMap(
@@ -120,7 +168,7 @@ func (q *Queries) UpdateCompany(
 				`phone`:   data.Phone,
 			},
 		).
-		Where(sq.Eq{`id`: compID}).
+		Where(sq.Eq{`id`: compID, `tenant_id`: tenantID}).
 		Suffix(` RETURNING id`)
 
 	query, args, err := builder.ToSql()
@@ -130,7 +178,7 @@ func (q *Queries) UpdateCompany(
 	var id uint64
 	err = q.tx.QueryRow(ctx, query, args...).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("query: %w", err)
+		return 0, fmt.Errorf("query: %w", codeError(err))
 	}
 
 	return id, nil
@@ -140,6 +188,13 @@ func (q *Queries) GetCompanyByID(
 	ctx context.Context,
 	compID uint64,
 ) (*Company, error) {
+	ctx, cancel := q.queryContext(ctx, `GetCompanyByID`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	builder := q.builder.
 		Select(
 			`id`,
@@ -148,9 +203,10 @@ func (q *Queries) GetCompanyByID(
 			`country`,
 			`website`,
 			`phone`,
+			`COALESCE(owner_id, 0) AS owner_id`,
 		).
 		From("companies").
-		Where(sq.Eq{`id`: compID})
+		Where(sq.Eq{`id`: compID, `tenant_id`: tenantID})
 
 	query, args, err := builder.ToSql()
 	if err != nil {
@@ -170,9 +226,16 @@ func (q *Queries) DeleteCompany(
 	ctx context.Context,
 	compID uint64,
 ) error {
+	ctx, cancel := q.queryContext(ctx, `DeleteCompany`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
 	builder := q.builder.
 		Delete("companies").
-		Where(sq.Eq{`id`: compID})
+		Where(sq.Eq{`id`: compID, `tenant_id`: tenantID})
 
 	query, args, err := builder.ToSql()
 	if err != nil {
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// claimIdempotencyKeySQL stores the key unless another request holds it. Expired keys are replaced,
+	// keys of requests which didn't complete before staleBefore are taken over by retries of the same request.
+	claimIdempotencyKeySQL = `INSERT INTO idempotency_keys (scope, key, request_hash, lock_id, locked_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, lock_id = EXCLUDED.lock_id, response = NULL,
+			locked_at = EXCLUDED.locked_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < EXCLUDED.locked_at
+			OR (idempotency_keys.response IS NULL AND idempotency_keys.locked_at < $7
+				AND idempotency_keys.request_hash = EXCLUDED.request_hash)
+		RETURNING lock_id`
+
+	getIdempotencyKeySQL = `SELECT request_hash, response FROM idempotency_keys WHERE scope = $1 AND key = $2`
+
+	saveIdempotentResponseSQL = `UPDATE idempotency_keys SET response = $4
+		WHERE scope = $1 AND key = $2 AND lock_id = $3 AND response IS NULL`
+
+	deleteIdempotencyKeySQL = `DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND lock_id = $3 AND response IS NULL`
+
+	deleteExpiredIdempotencyKeysSQL = `DELETE FROM idempotency_keys WHERE expires_at < $1`
+)
+
+type IdempotencyQueries interface {
+	ClaimIdempotencyKey(ctx context.Context, claim IdempotencyClaim) (bool, error)
+	GetIdempotencyKey(ctx context.Context, scope, key string) (*IdempotencyKey, error)
+	SaveIdempotentResponse(ctx context.Context, scope, key, lockID string, response []byte) error
+	DeleteIdempotencyKey(ctx context.Context, scope, key, lockID string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error
+}
+
+// IdempotencyClaim claim of the key by the request
+type IdempotencyClaim struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	// LockID identifies the request holding the key
+	LockID    string
+	Now       time.Time
+	ExpiresAt time.Time
+	// StaleBefore requests holding the key since before the time are considered failed
+	StaleBefore time.Time
+}
+
+type IdempotencyKey struct {
+	RequestHash string
+	// Response stored response, nil while the request holding the key is in progress
+	Response []byte
+}
+
+// ClaimIdempotencyKey claims the key for the request, false if the key is held or completed by another request
+func (q *Queries) ClaimIdempotencyKey(ctx context.Context, claim IdempotencyClaim) (bool, error) {
+	ctx, cancel := q.queryContext(ctx, `ClaimIdempotencyKey`, queryWrite)
+	defer cancel()
+
+	var lockID string
+	err := pgxscan.Get(ctx, q.tx, &lockID, claimIdempotencyKeySQL,
+		claim.Scope, claim.Key, claim.RequestHash, claim.LockID, claim.Now, claim.ExpiresAt, claim.StaleBefore,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("query: %w", err)
+	}
+
+	return true, nil
+}
+
+func (q *Queries) GetIdempotencyKey(ctx context.Context, scope, key string) (*IdempotencyKey, error) {
+	ctx, cancel := q.queryContext(ctx, `GetIdempotencyKey`, queryRead)
+	defer cancel()
+
+	var res IdempotencyKey
+	err := pgxscan.Get(ctx, q.tx, &res, getIdempotencyKeySQL, scope, key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return &res, nil
+}
+
+// SaveIdempotentResponse stores response of the request holding the key
+func (q *Queries) SaveIdempotentResponse(ctx context.Context, scope, key, lockID string, response []byte) error {
+	ctx, cancel := q.queryContext(ctx, `SaveIdempotentResponse`, queryWrite)
+	defer cancel()
+
+	tag, err := q.tx.Exec(ctx, saveIdempotentResponseSQL, scope, key, lockID, response)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyKey releases the key held by the request, so the request can be retried
+func (q *Queries) DeleteIdempotencyKey(ctx context.Context, scope, key, lockID string) error {
+	ctx, cancel := q.queryContext(ctx, `DeleteIdempotencyKey`, queryWrite)
+	defer cancel()
+
+	_, err := q.tx.Exec(ctx, deleteIdempotencyKeySQL, scope, key, lockID)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	ctx, cancel := q.queryContext(ctx, `DeleteExpiredIdempotencyKeys`, queryWrite)
+	defer cancel()
+
+	_, err := q.tx.Exec(ctx, deleteExpiredIdempotencyKeysSQL, now)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+func TestQueries_ClaimIdempotencyKey(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	claim := IdempotencyClaim{
+		Scope:       `acme|user:1`,
+		Key:         `k1`,
+		RequestHash: `hash`,
+		LockID:      `lock`,
+		Now:         now,
+		ExpiresAt:   now.Add(24 * time.Hour),
+		StaleBefore: now.Add(-time.Minute),
+	}
+	tests := []struct {
+		name string
+		rows *pgxmock.Rows
+		want bool
+	}{
+		{name: `Claimed`, rows: pgxmock.NewRows([]string{`lock_id`}).AddRow(`lock`), want: true},
+		{name: `Held by other request`, rows: pgxmock.NewRows([]string{`lock_id`})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgxMock, db := newTestDB(t, &config.DB{})
+			pgxMock.ExpectQuery("INSERT INTO idempotency_keys .+ ON CONFLICT .+ RETURNING lock_id").
+				WithArgs(`acme|user:1`, `k1`, `hash`, `lock`, now, now.Add(24*time.Hour), now.Add(-time.Minute)).
+				WillReturnRows(tt.rows)
+
+			got, err := db.Queries.(*Queries).ClaimIdempotencyKey(context.Background(), claim)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, pgxMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestQueries_SaveIdempotentResponseLostKey(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectExec("UPDATE idempotency_keys SET response").
+		WithArgs(`acme|user:1`, `k1`, `lock`, []byte(`{}`)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := db.Queries.(*Queries).SaveIdempotentResponse(context.Background(), `acme|user:1`, `k1`, `lock`, []byte(`{}`))
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
@@ -13,6 +13,29 @@ type MockCompaniesQueries struct {
 	mock.Mock
 }
 
+// BulkCreateCompanies provides a mock function with given fields: ctx, data
+func (_m *MockCompaniesQueries) BulkCreateCompanies(ctx context.Context, data []Company) (*BulkResult, error) {
+	ret := _m.Called(ctx, data)
+
+	var r0 *BulkResult
+	if rf, ok := ret.Get(0).(func(context.Context, []Company) *BulkResult); ok {
+		r0 = rf(ctx, data)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*BulkResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []Company) error); ok {
+		r1 = rf(ctx, data)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateCompany provides a mock function with given fields: ctx, params
 func (_m *MockCompaniesQueries) CreateCompany(ctx context.Context, params Company) (uint64, error) {
 	ret := _m.Called(ctx, params)
@@ -34,6 +57,20 @@ func (_m *MockCompaniesQueries) CreateCompany(ctx context.Context, params Compan
 	return r0, r1
 }
 
+// DeleteCollaborator provides a mock function with given fields: ctx, compID, userID
+func (_m *MockCompaniesQueries) DeleteCollaborator(ctx context.Context, compID uint64, userID int64) error {
+	ret := _m.Called(ctx, compID, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64) error); ok {
+		r0 = rf(ctx, compID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteCompany provides a mock function with given fields: ctx, compID
 func (_m *MockCompaniesQueries) DeleteCompany(ctx context.Context, compID uint64) error {
 	ret := _m.Called(ctx, compID)
@@ -48,6 +85,29 @@ func (_m *MockCompaniesQueries) DeleteCompany(ctx context.Context, compID uint64
 	return r0
 }
 
+// GetCollaborators provides a mock function with given fields: ctx, compID
+func (_m *MockCompaniesQueries) GetCollaborators(ctx context.Context, compID uint64) ([]*Collaborator, error) {
+	ret := _m.Called(ctx, compID)
+
+	var r0 []*Collaborator
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*Collaborator); ok {
+		r0 = rf(ctx, compID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*Collaborator)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, compID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCompanies provides a mock function with given fields: ctx, params
 func (_m *MockCompaniesQueries) GetCompanies(ctx context.Context, params Company) ([]*Company, error) {
 	ret := _m.Called(ctx, params)
@@ -71,6 +131,29 @@ func (_m *MockCompaniesQueries) GetCompanies(ctx context.Context, params Company
 	return r0, r1
 }
 
+// GetCompanyAccess provides a mock function with given fields: ctx, compID, userID
+func (_m *MockCompaniesQueries) GetCompanyAccess(ctx context.Context, compID uint64, userID int64) (*CompanyAccess, error) {
+	ret := _m.Called(ctx, compID, userID)
+
+	var r0 *CompanyAccess
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64) *CompanyAccess); ok {
+		r0 = rf(ctx, compID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*CompanyAccess)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, int64) error); ok {
+		r1 = rf(ctx, compID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCompanyByID provides a mock function with given fields: ctx, compID
 func (_m *MockCompaniesQueries) GetCompanyByID(ctx context.Context, compID uint64) (*Company, error) {
 	ret := _m.Called(ctx, compID)
@@ -94,6 +177,20 @@ func (_m *MockCompaniesQueries) GetCompanyByID(ctx context.Context, compID uint6
 	return r0, r1
 }
 
+// SetCollaborator provides a mock function with given fields: ctx, compID, userID, role
+func (_m *MockCompaniesQueries) SetCollaborator(ctx context.Context, compID uint64, userID int64, role string) error {
+	ret := _m.Called(ctx, compID, userID, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64, string) error); ok {
+		r0 = rf(ctx, compID, userID, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateCompany provides a mock function with given fields: ctx, compID, data
 func (_m *MockCompaniesQueries) UpdateCompany(ctx context.Context, compID uint64, data Company) (uint64, error) {
 	ret := _m.Called(ctx, compID, data)
@@ -3,25 +3,33 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
 
 	"github.com/M-Fisher/companies_api/app/config"
 )
 
 type DB struct {
-	pool    DBConn
-	Queries Queriable
-	Log     *zap.Logger
+	pool     DBConn
+	Queries  Queriable
+	Log      *zap.Logger
+	replicas []*replica
+	next     uint32
+	stop     chan struct{}
+	txOpts   TxOptions
+	timeouts queryTimeouts
 }
 
 type txConn interface {
 	Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error)
 	Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
 }
 
 type DBConn interface {
@@ -30,8 +38,10 @@ type DBConn interface {
 	Close(ctx context.Context) error
 }
 type Queries struct {
-	builder *sq.StatementBuilderType
-	tx      txConn
+	builder  *sq.StatementBuilderType
+	tx       txConn
+	state    *txState
+	timeouts queryTimeouts
 }
 
 type Queriable interface {
@@ -40,9 +50,11 @@ type Queriable interface {
 
 func NewPostgres(conf *config.DB, log *zap.Logger) (*DB, error) {
 	storage := &DB{
-		Log: log,
+		Log:      log,
+		txOpts:   defaultTxOptions(conf),
+		timeouts: newQueryTimeouts(conf),
 	}
-	dsn := formDbURI(conf)
+	dsn := formDbURI(conf, conf.Host)
 	log.Debug("creating database connection", zap.String("dsn", conf.Host))
 	db, err := checkDB(dsn, conf)
 	if err != nil {
@@ -52,74 +64,95 @@ func NewPostgres(conf *config.DB, log *zap.Logger) (*DB, error) {
 
 	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 	storage.Queries = &Queries{
-		builder: &builder,
-		tx:      db,
+		builder:  &builder,
+		tx:       db,
+		timeouts: storage.timeouts,
 	}
 	storage.pool = db
 
+	for _, host := range conf.ReplicaHosts {
+		storage.replicas = append(storage.replicas, newReplica(conf, host, log))
+	}
+	if len(storage.replicas) > 0 {
+		storage.stop = make(chan struct{})
+		go storage.watchReplicas(conf.ReplicaHealthInterval)
+	}
+
 	return storage, nil
 }
 
 func (d *DB) Close(ctx context.Context) error {
 	d.Log.Info("Closing DB connection")
+	if d.stop != nil {
+		close(d.stop)
+	}
+	for _, r := range d.replicas {
+		if err := r.close(ctx); err != nil {
+			d.Log.Error("Failed to close replica connection", zap.String("host", r.host), zap.Error(err))
+		}
+	}
 	return d.pool.Close(ctx)
 }
 
 func NewTestPostgres(pool DBConn, conf *config.DB, log *zap.Logger) (*DB, error) {
 	storage := &DB{
-		Log: log,
+		Log:      log,
+		txOpts:   defaultTxOptions(conf),
+		timeouts: newQueryTimeouts(conf),
 	}
 	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 	storage.Queries = &Queries{
-		builder: &builder,
-		tx:      pool,
+		builder:  &builder,
+		tx:       pool,
+		timeouts: storage.timeouts,
 	}
 	storage.pool = pool
 
 	return storage, nil
 }
 
-func (db *DB) ExecTx(ctx context.Context, f func(q *Queries) error) (err error) {
-	tx, err := db.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+// checkDB Check the DB connection via a given DSN string.
+func checkDB(dsn string, conf *config.DB) (DBConn, error) {
+	poolConf, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err == nil {
-			err = tx.Commit(ctx)
-			if err != nil {
-				err = tx.Rollback(ctx)
-			}
-		} else {
-			txErr := tx.Rollback(ctx)
-			if txErr != nil {
-				err = fmt.Errorf("rollback failed %s; %w", txErr.Error(), err)
-			}
-		}
-	}()
-
-	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	q := &Queries{tx: tx, builder: &builder}
-
-	err = f(q)
-
-	return err
-}
+	poolConf.MaxConns = int32(conf.MaxConns)
+	poolConf.MaxConnIdleTime = conf.MaxIdleConnTime
+	poolConf.MaxConnLifetime = conf.ConnMaxLifetime
+	if conf.StatementTimeout > 0 {
+		// server side limit for queries which were not cancelled by client in time
+		poolConf.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(conf.StatementTimeout.Milliseconds(), 10)
+	}
+	poolConf.ConnConfig.Logger = newQueryTracer(conf.SlowQueryThreshold)
+	poolConf.ConnConfig.LogLevel = pgx.LogLevelInfo
 
-// checkDB Check the DB connection via a given DSN string.
-func checkDB(dsn string, conf *config.DB) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(context.Background(), dsn)
+	pool, err := pgxpool.ConnectConfig(context.Background(), poolConf)
 	if err != nil {
 		return nil, err
 	}
+	if err = pool.Ping(context.Background()); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &poolConn{Pool: pool}, nil
+}
+
+// poolConn adapts pgxpool.Pool to DBConn
+type poolConn struct {
+	*pgxpool.Pool
+}
 
-	return conn, nil
+func (p *poolConn) Close(_ context.Context) error {
+	p.Pool.Close()
+	return nil
 }
 
-func formDbURI(conf *config.DB) string {
+func formDbURI(conf *config.DB, host string) string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s/%s?sslmode=disable&connect_timeout=10",
-		conf.User, conf.Password, conf.Host, conf.Database,
+		conf.User, conf.Password, host, conf.Database,
 	)
 }
 
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+var errReplicaNotConnected = errors.New("replica is not connected")
+
+type replica struct {
+	host    string
+	dsn     string
+	conf    *config.DB
+	log     *zap.Logger
+	mu      sync.RWMutex
+	conn    DBConn
+	queries Queriable
+	err     error
+}
+
+func newReplica(conf *config.DB, host string, log *zap.Logger) *replica {
+	r := &replica{
+		host: host,
+		dsn:  formDbURI(conf, host),
+		conf: conf,
+		log:  log.With(zap.String("replica", host)),
+		err:  errReplicaNotConnected,
+	}
+	r.check(context.Background())
+	return r
+}
+
+// check pings the replica and reconnects it when the connection is lost
+func (r *replica) check(ctx context.Context) {
+	r.mu.RLock()
+	conn := r.conn
+	r.mu.RUnlock()
+
+	if conn == nil {
+		newConn, err := checkDB(r.dsn, r.conf)
+		if err != nil {
+			r.setStatus(nil, err)
+			return
+		}
+		r.setStatus(newConn, nil)
+		return
+	}
+
+	_, err := conn.Exec(ctx, `SELECT 1`)
+	if err != nil {
+		// the connection is useless after failure, so it is dropped and recreated on the next check
+		_ = conn.Close(ctx)
+		conn = nil
+	}
+	r.setStatus(conn, err)
+}
+
+func (r *replica) setStatus(conn DBConn, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if conn == nil && err == nil {
+		err = errReplicaNotConnected
+	}
+	if err != nil && r.err == nil {
+		r.log.Warn("DB replica is unhealthy, reads are moved to other hosts", zap.Error(err))
+	}
+	if err == nil && r.err != nil {
+		r.log.Info("DB replica is available for reads")
+	}
+	r.conn = conn
+	r.err = err
+	if conn != nil {
+		builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+		r.queries = &Queries{
+			builder:  &builder,
+			tx:       conn,
+			timeouts: newQueryTimeouts(r.conf),
+		}
+	} else {
+		r.queries = nil
+	}
+}
+
+func (r *replica) status() (Queriable, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.queries, r.err
+}
+
+func (r *replica) close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close(ctx)
+	r.conn = nil
+	r.queries = nil
+	r.err = errReplicaNotConnected
+	return err
+}
+
+func (d *DB) watchReplicas(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			for _, r := range d.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				r.check(ctx)
+				cancel()
+			}
+		}
+	}
+}
+
+// ReadQueries returns queries bound to a healthy replica picked in round-robin order.
+// Falls back to the primary when there are no replicas or all of them are unhealthy.
+// Must not be used for writes and inside transactions.
+func (d *DB) ReadQueries() Queriable {
+	n := len(d.replicas)
+	if n == 0 {
+		return d.Queries
+	}
+	start := atomic.AddUint32(&d.next, 1)
+	for i := 0; i < n; i++ {
+		r := d.replicas[(int(start)+i)%n]
+		if q, err := r.status(); err == nil {
+			return q
+		}
+	}
+	d.Log.Debug("No healthy DB replicas, reading from primary")
+	return d.Queries
+}
+
+// ReplicasStatus returns last health check result for every replica by host
+func (d *DB) ReplicasStatus() map[string]error {
+	res := make(map[string]error, len(d.replicas))
+	for _, r := range d.replicas {
+		_, err := r.status()
+		res[r.host] = err
+	}
+	return res
+}
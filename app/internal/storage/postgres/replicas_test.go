@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestDB_ReadQueries(t *testing.T) {
+	primary := new(MockCompaniesQueries)
+	replicaQueries := new(MockCompaniesQueries)
+	tests := []struct {
+		name     string
+		replicas []*replica
+		want     Queriable
+	}{
+		{
+			name: `No replicas - primary`,
+			want: primary,
+		},
+		{
+			name: `Healthy replica`,
+			replicas: []*replica{
+				{host: `replica1`, err: errors.New(`down`)},
+				{host: `replica2`, queries: replicaQueries},
+			},
+			want: replicaQueries,
+		},
+		{
+			name: `All replicas unhealthy - primary`,
+			replicas: []*replica{
+				{host: `replica1`, err: errors.New(`down`)},
+				{host: `replica2`, err: errReplicaNotConnected},
+			},
+			want: primary,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &DB{
+				Queries:  primary,
+				Log:      zap.NewExample(),
+				replicas: tt.replicas,
+			}
+			for i := 0; i < 3; i++ {
+				assert.Same(t, tt.want, d.ReadQueries())
+			}
+		})
+	}
+}
+
+func TestDB_ReplicasStatus(t *testing.T) {
+	d := &DB{
+		replicas: []*replica{
+			{host: `replica1`, err: errors.New(`down`)},
+			{host: `replica2`},
+		},
+	}
+	assert.Equal(t, map[string]error{
+		`replica1`: errors.New(`down`),
+		`replica2`: nil,
+	}, d.ReplicasStatus())
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+)
+
+const (
+	revokeTokenSQL = `INSERT INTO revoked_tokens (jti, tenant_id, user_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (jti) DO NOTHING`
+
+	// revokeUserTokensSQL moves revocation time of the user forward only
+	revokeUserTokensSQL = `INSERT INTO user_token_revocations (tenant_id, user_id, revoked_before)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, user_id)
+		DO UPDATE SET revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before)`
+
+	deleteExpiredRevokedTokensSQL = `DELETE FROM revoked_tokens WHERE expires_at < now()`
+)
+
+type RevocationsQueries interface {
+	RevokeToken(ctx context.Context, jti string, userID int64, expiresAt *time.Time) error
+	RevokeUserTokens(ctx context.Context, userID int64, before time.Time) error
+	GetRevokedTokens(ctx context.Context) ([]*RevokedToken, error)
+	GetUserRevocations(ctx context.Context) ([]*UserRevocation, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) error
+}
+
+type RevokedToken struct {
+	JTI string
+	// ExpiresAt expiry of the token, nil if the token never expires
+	ExpiresAt *time.Time
+}
+
+// UserRevocation tokens of the user issued before RevokedBefore are revoked
+type UserRevocation struct {
+	TenantID      string
+	UserID        int64
+	RevokedBefore time.Time
+}
+
+// RevokeToken revokes token of the user from the caller's tenant
+func (q *Queries) RevokeToken(ctx context.Context, jti string, userID int64, expiresAt *time.Time) error {
+	ctx, cancel := q.queryContext(ctx, `RevokeToken`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.tx.Exec(ctx, revokeTokenSQL, jti, tenantID, userID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens revokes tokens of the user from the caller's tenant issued before the time
+func (q *Queries) RevokeUserTokens(ctx context.Context, userID int64, before time.Time) error {
+	ctx, cancel := q.queryContext(ctx, `RevokeUserTokens`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.tx.Exec(ctx, revokeUserTokensSQL, tenantID, userID, before)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
+
+// GetRevokedTokens returns revoked tokens of all tenants which are not expired yet
+func (q *Queries) GetRevokedTokens(ctx context.Context) ([]*RevokedToken, error) {
+	ctx, cancel := q.queryContext(ctx, `GetRevokedTokens`, queryRead)
+	defer cancel()
+
+	res := []*RevokedToken{}
+	builder := q.builder.
+		Select(
+			`jti`,
+			`expires_at`,
+		).
+		From("revoked_tokens").
+		Where(`expires_at IS NULL OR expires_at > now()`)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	err = pgxscan.Select(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetUserRevocations returns revocations of all tenants
+func (q *Queries) GetUserRevocations(ctx context.Context) ([]*UserRevocation, error) {
+	ctx, cancel := q.queryContext(ctx, `GetUserRevocations`, queryRead)
+	defer cancel()
+
+	res := []*UserRevocation{}
+	builder := q.builder.
+		Select(
+			`tenant_id`,
+			`user_id`,
+			`revoked_before`,
+		).
+		From("user_token_revocations")
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	err = pgxscan.Select(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return res, nil
+}
+
+func (q *Queries) DeleteExpiredRevokedTokens(ctx context.Context) error {
+	ctx, cancel := q.queryContext(ctx, `DeleteExpiredRevokedTokens`, queryWrite)
+	defer cancel()
+
+	_, err := q.tx.Exec(ctx, deleteExpiredRevokedTokensSQL)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+func TestQueries_RevokeUserTokens(t *testing.T) {
+	before := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectExec("INSERT INTO user_token_revocations .+ GREATEST").
+		WithArgs(`acme`, int64(4), before).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	err := db.Queries.(*Queries).RevokeUserTokens(ctx, 4, before)
+
+	assert.NoError(t, err)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_RevokeTokenWithoutTenant(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+
+	err := db.Queries.(*Queries).RevokeToken(context.Background(), `t1`, 4, nil)
+
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+type ServiceAccountsQueries interface {
+	CreateServiceAccount(ctx context.Context, data ServiceAccount) (int64, error)
+	GetServiceAccounts(ctx context.Context) ([]*ServiceAccount, error)
+	GetServiceAccountByClientID(ctx context.Context, clientID string) (*ServiceAccount, error)
+	UpdateServiceAccountSecret(ctx context.Context, id int64, secretHash string) (*ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, id int64) error
+}
+
+type ServiceAccount struct {
+	ID         int64
+	TenantID   string
+	Name       string
+	ClientID   string
+	SecretHash string
+	Scopes     []string
+	Disabled   bool
+	CreatedAt  time.Time
+}
+
+var serviceAccountColumns = []string{
+	`id`,
+	`tenant_id`,
+	`name`,
+	`client_id`,
+	`secret_hash`,
+	`scopes`,
+	`disabled`,
+	`created_at`,
+}
+
+func (q *Queries) CreateServiceAccount(
+	ctx context.Context,
+	data ServiceAccount,
+) (int64, error) {
+	ctx, cancel := q.queryContext(ctx, `CreateServiceAccount`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	builder := q.builder.
+		Insert("service_accounts").
+		SetMap(
+			map[string]any{
+				`tenant_id`:   tenantID,
+				`name`:        data.Name,
+				`client_id`:   data.ClientID,
+				`secret_hash`: data.SecretHash,
+				`scopes`:      data.Scopes,
+			},
+		).
+		Suffix(` RETURNING id`)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("build query: %w", err)
+	}
+	var id int64
+	err = q.tx.QueryRow(ctx, query, args...).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	return id, nil
+}
+
+func (q *Queries) GetServiceAccounts(ctx context.Context) ([]*ServiceAccount, error) {
+	ctx, cancel := q.queryContext(ctx, `GetServiceAccounts`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*ServiceAccount{}
+	builder := q.builder.
+		Select(serviceAccountColumns...).
+		From("service_accounts").
+		Where(sq.Eq{`tenant_id`: tenantID}).
+		OrderBy(`id`)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	err = pgxscan.Select(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetServiceAccountByClientID finds account of any tenant, it is used to authenticate the client
+func (q *Queries) GetServiceAccountByClientID(
+	ctx context.Context,
+	clientID string,
+) (*ServiceAccount, error) {
+	ctx, cancel := q.queryContext(ctx, `GetServiceAccountByClientID`, queryRead)
+	defer cancel()
+
+	builder := q.builder.
+		Select(serviceAccountColumns...).
+		From("service_accounts").
+		Where(sq.Eq{`client_id`: clientID})
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	var res ServiceAccount
+	err = pgxscan.Get(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return &res, nil
+}
+
+// UpdateServiceAccountSecret replaces secret of the account, the old secret stops working immediately
+func (q *Queries) UpdateServiceAccountSecret(
+	ctx context.Context,
+	id int64,
+	secretHash string,
+) (*ServiceAccount, error) {
+	ctx, cancel := q.queryContext(ctx, `UpdateServiceAccountSecret`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := q.builder.
+		Update("service_accounts").
+		Set(`secret_hash`, secretHash).
+		Set(`updated_at`, sq.Expr(`now()`)).
+		Where(sq.Eq{`id`: id, `tenant_id`: tenantID}).
+		Suffix(`RETURNING ` + strings.Join(serviceAccountColumns, `, `))
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	var res ServiceAccount
+	err = pgxscan.Get(ctx, q.tx, &res, query, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return &res, nil
+}
+
+func (q *Queries) DisableServiceAccount(ctx context.Context, id int64) error {
+	ctx, cancel := q.queryContext(ctx, `DisableServiceAccount`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	builder := q.builder.
+		Update("service_accounts").
+		Set(`disabled`, true).
+		Set(`updated_at`, sq.Expr(`now()`)).
+		Where(sq.Eq{`id`: id, `tenant_id`: tenantID})
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("build query: %w", err)
+	}
+
+	tag, err := q.tx.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
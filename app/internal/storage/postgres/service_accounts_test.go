@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+func TestQueries_CreateServiceAccount(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("^INSERT INTO service_accounts \\(client_id,name,scopes,secret_hash,tenant_id\\) VALUES \\(\\$1,\\$2,\\$3,\\$4,\\$5\\) RETURNING id$").
+		WithArgs(`sa_1`, `importer`, []string{`companies:write`}, `hash`, `acme`).
+		WillReturnRows(pgxMock.NewRows([]string{`id`}).AddRow(int64(3)))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	id, err := db.Queries.(*Queries).CreateServiceAccount(ctx, ServiceAccount{
+		Name:       `importer`,
+		ClientID:   `sa_1`,
+		SecretHash: `hash`,
+		Scopes:     []string{`companies:write`},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_GetServiceAccountByClientID(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("^SELECT .+ FROM service_accounts WHERE client_id = \\$1$").
+		WithArgs(`sa_1`).
+		WillReturnRows(pgxMock.NewRows(serviceAccountColumns).
+			AddRow(int64(3), `acme`, `importer`, `sa_1`, `hash`, []string{`companies:write`}, false, created))
+
+	got, err := db.Queries.(*Queries).GetServiceAccountByClientID(context.Background(), `sa_1`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &ServiceAccount{
+		ID:         3,
+		TenantID:   `acme`,
+		Name:       `importer`,
+		ClientID:   `sa_1`,
+		SecretHash: `hash`,
+		Scopes:     []string{`companies:write`},
+		CreatedAt:  created,
+	}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_GetServiceAccountByClientIDNotFound(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("SELECT .+ FROM service_accounts").
+		WillReturnRows(pgxMock.NewRows(serviceAccountColumns))
+
+	_, err := db.Queries.(*Queries).GetServiceAccountByClientID(context.Background(), `sa_1`)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQueries_DisableServiceAccountOtherTenant(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectExec("^UPDATE service_accounts SET disabled = \\$1, updated_at = now\\(\\) WHERE id = \\$2 AND tenant_id = \\$3$").
+		WithArgs(true, int64(3), `acme`).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	err := db.Queries.(*Queries).DisableServiceAccount(ctx, 3)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
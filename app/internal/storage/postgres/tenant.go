@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+// ErrTenantRequired query was called without caller identity, so it can't be scoped by tenant
+var ErrTenantRequired = errors.New("tenant is required")
+
+// tenantFromContext returns tenant of the caller, every companies query is scoped by it
+func tenantFromContext(ctx context.Context) (string, error) {
+	id, err := callerFromContext(ctx)
+	return id.TenantID, err
+}
+
+func callerFromContext(ctx context.Context) (identity.Identity, error) {
+	id, ok := identity.FromContext(ctx)
+	if !ok || id.TenantID == "" {
+		return identity.Identity{}, ErrTenantRequired
+	}
+	return id, nil
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+func TestQueries_TenantRequired(t *testing.T) {
+	_, db := newTestDB(t, &config.DB{})
+
+	_, err := db.Queries.GetCompanies(context.Background(), Company{})
+	assert.ErrorIs(t, err, ErrTenantRequired)
+
+	err = db.Queries.DeleteCompany(identity.ToContext(context.Background(), identity.Identity{UserID: 1}), 1)
+	assert.ErrorIs(t, err, ErrTenantRequired)
+}
+
+func TestQueries_GetCompanyByIDTenantScoped(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("^SELECT .+ FROM companies WHERE id = \\$1 AND tenant_id = \\$2$").
+		WithArgs(uint64(1), `acme`).
+		WillReturnRows(pgxMock.NewRows([]string{`id`, `name`}).AddRow(uint64(1), `test`))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	got, err := db.Queries.GetCompanyByID(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Company{ID: 1, Name: `test`}, got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
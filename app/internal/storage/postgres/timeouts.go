@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+type queryType int
+
+const (
+	queryRead queryType = iota + 1
+	querySearch
+	queryWrite
+	queryBulk
+)
+
+type queryTimeouts struct {
+	read   time.Duration
+	search time.Duration
+	write  time.Duration
+	bulk   time.Duration
+}
+
+func newQueryTimeouts(conf *config.DB) queryTimeouts {
+	return queryTimeouts{
+		read:   conf.ReadTimeout,
+		search: conf.SearchTimeout,
+		write:  conf.WriteTimeout,
+		bulk:   conf.BulkTimeout,
+	}
+}
+
+// queryContext names the query for tracing and limits its execution time by query type.
+// Cancelling the context makes pgx send cancel request to postgres, so the query is stopped on server too.
+func (q *Queries) queryContext(ctx context.Context, name string, t queryType) (context.Context, context.CancelFunc) {
+	ctx = withQueryName(ctx, name)
+	var timeout time.Duration
+	switch t {
+	case queryRead:
+		timeout = q.timeouts.read
+	case querySearch:
+		timeout = q.timeouts.search
+	case queryWrite:
+		timeout = q.timeouts.write
+	case queryBulk:
+		timeout = q.timeouts.bulk
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+func TestQueries_queryContext(t *testing.T) {
+	q := &Queries{
+		timeouts: newQueryTimeouts(&config.DB{
+			ReadTimeout:   time.Second,
+			SearchTimeout: 5 * time.Second,
+		}),
+	}
+	tests := []struct {
+		name      string
+		queryType queryType
+		want      time.Duration
+	}{
+		{
+			name:      `Read query`,
+			queryType: queryRead,
+			want:      time.Second,
+		},
+		{
+			name:      `Search query`,
+			queryType: querySearch,
+			want:      5 * time.Second,
+		},
+		{
+			name:      `Timeout is not set`,
+			queryType: queryWrite,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := q.queryContext(context.Background(), `test`, tt.queryType)
+			defer cancel()
+			assert.Equal(t, `test`, queryNameFromContext(ctx))
+			deadline, ok := ctx.Deadline()
+			if tt.want == 0 {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(tt.want), deadline, 100*time.Millisecond)
+		})
+	}
+}
+
+func TestQueries_GetCompaniesCancelled(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{SearchTimeout: time.Second})
+	pgxMock.ExpectQuery("SELECT .+ FROM companies").WillDelayFor(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := db.Queries.GetCompanies(ctx, Company{})
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
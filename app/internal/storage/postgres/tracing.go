@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+	"github.com/M-Fisher/companies_api/app/internal/metrics"
+)
+
+type queryNameKeyType int
+
+const queryNameKey queryNameKeyType = iota
+
+const unnamedQuery = `other`
+
+var (
+	queryDuration = metrics.NewHistogramVec("db_query_duration_seconds", metrics.DurationBuckets)
+	queryRows     = metrics.NewHistogramVec("db_query_rows", []float64{0, 1, 10, 100, 1000, 10000, 100000})
+	queryErrors   = metrics.NewCounter("db_query_errors")
+)
+
+func withQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey, name)
+}
+
+func queryNameFromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(queryNameKey).(string); ok {
+		return name
+	}
+	return unnamedQuery
+}
+
+// queryTracer receives pgx query logs, records query metrics and logs slow queries
+type queryTracer struct {
+	slowThreshold time.Duration
+}
+
+func newQueryTracer(slowThreshold time.Duration) *queryTracer {
+	return &queryTracer{
+		slowThreshold: slowThreshold,
+	}
+}
+
+// Log implements pgx.Logger
+func (t *queryTracer) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	switch msg {
+	case "Query", "Exec", "CopyFrom", "SendBatch":
+	default:
+		return
+	}
+	duration, ok := data["time"].(time.Duration)
+	if !ok {
+		return
+	}
+	name := queryNameFromContext(ctx)
+	rows := rowsAffected(data)
+	err, _ := data["err"].(error)
+
+	queryDuration.Observe(name, duration.Seconds())
+	if rows >= 0 {
+		queryRows.Observe(name, float64(rows))
+	}
+	if err != nil {
+		queryErrors.Add(name, 1)
+	}
+
+	if t.slowThreshold <= 0 || duration < t.slowThreshold {
+		return
+	}
+	fields := []zap.Field{
+		zap.String("query_name", name),
+		zap.Duration("duration", duration),
+		zap.Int64("rows", rows),
+	}
+	if sql, ok := data["sql"].(string); ok {
+		// only SQL with placeholders is logged, arguments may contain user data
+		fields = append(fields, zap.String("sql", strings.Join(strings.Fields(sql), " ")))
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	logger.FromContext(ctx).Warn("Slow query", fields...)
+}
+
+func rowsAffected(data map[string]interface{}) int64 {
+	switch v := data["rowCount"].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	}
+	if tag, ok := data["commandTag"].(pgconn.CommandTag); ok {
+		return tag.RowsAffected()
+	}
+	return -1
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/M-Fisher/companies_api/app/internal/logger"
+)
+
+func TestQueryTracer_Log(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logger.ToContext(context.Background(), zap.New(core).With(zap.String("trace_id", "TRACE")))
+	tracer := newQueryTracer(100 * time.Millisecond)
+
+	tracer.Log(withQueryName(ctx, `TestFastQuery`), pgx.LogLevelInfo, "Query", map[string]interface{}{
+		"sql":      "SELECT id FROM companies WHERE name = $1",
+		"args":     []interface{}{"secret"},
+		"time":     time.Millisecond,
+		"rowCount": 2,
+	})
+	tracer.Log(withQueryName(ctx, `TestSlowQuery`), pgx.LogLevelError, "Query", map[string]interface{}{
+		"sql":  "SELECT id\n\tFROM companies WHERE name = $1",
+		"args": []interface{}{"secret"},
+		"time": time.Second,
+		"err":  errors.New("canceled"),
+	})
+	tracer.Log(ctx, pgx.LogLevelInfo, "Dialing PostgreSQL server", map[string]interface{}{"host": "localhost"})
+
+	if assert.Equal(t, 1, logs.Len()) {
+		entry := logs.All()[0]
+		assert.Equal(t, "Slow query", entry.Message)
+		fields := entry.ContextMap()
+		assert.Equal(t, "TRACE", fields["trace_id"])
+		assert.Equal(t, "TestSlowQuery", fields["query_name"])
+		assert.Equal(t, "SELECT id FROM companies WHERE name = $1", fields["sql"])
+		assert.Equal(t, "canceled", fields["error"])
+		assert.NotContains(t, fields, "args")
+	}
+	assert.Contains(t, queryDuration.String(), `"TestFastQuery":{"count":1`)
+	assert.Contains(t, queryRows.String(), `"TestFastQuery":{"count":1,"sum":2`)
+	assert.Equal(t, "1", queryErrors.Get("TestSlowQuery").String())
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+// TxOptions transaction settings used by ExecTx
+type TxOptions struct {
+	IsoLevel pgx.TxIsoLevel
+	ReadOnly bool
+	// MaxRetries how many times the transaction is repeated after a retryable error
+	MaxRetries int
+	// Backoff delay before the first retry, doubled on every next one
+	Backoff time.Duration
+}
+
+type TxOption func(o *TxOptions)
+
+func WithIsoLevel(level pgx.TxIsoLevel) TxOption {
+	return func(o *TxOptions) {
+		o.IsoLevel = level
+	}
+}
+
+func WithReadOnly() TxOption {
+	return func(o *TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+func WithRetries(maxRetries int, backoff time.Duration) TxOption {
+	return func(o *TxOptions) {
+		o.MaxRetries = maxRetries
+		o.Backoff = backoff
+	}
+}
+
+func defaultTxOptions(conf *config.DB) TxOptions {
+	return TxOptions{
+		IsoLevel:   pgx.ReadCommitted,
+		MaxRetries: conf.TxMaxRetries,
+		Backoff:    conf.TxRetryBackoff,
+	}
+}
+
+func (o TxOptions) pgxOptions() pgx.TxOptions {
+	opts := pgx.TxOptions{IsoLevel: o.IsoLevel}
+	if o.ReadOnly {
+		opts.AccessMode = pgx.ReadOnly
+	}
+	return opts
+}
+
+type txState struct {
+	// sideEffects run after the transaction is committed
+	sideEffects []func() error
+}
+
+// retryableCodes SQLSTATEs after which the whole transaction can be safely repeated
+var retryableCodes = map[string]struct{}{
+	"40001": {}, // serialization_failure
+	"40P01": {}, // deadlock_detected
+	"08000": {}, // connection_exception
+	"08003": {}, // connection_does_not_exist
+	"08006": {}, // connection_failure
+}
+
+// IsRetryable reports whether the transaction failed with err can be repeated
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		_, ok := retryableCodes[pgErr.Code]
+		return ok
+	}
+	return pgconn.SafeToRetry(err)
+}
+
+// ExecTx runs f inside a transaction and repeats it on retryable errors.
+// Side effects queued by f via Queries.SideEffect run once after the commit. The transaction is committed
+// by then, so their failures are logged and don't fail ExecTx, otherwise callers would repeat committed writes.
+func (db *DB) ExecTx(ctx context.Context, f func(q *Queries) error, opts ...TxOption) error {
+	o := db.txOpts
+	if o.IsoLevel == "" {
+		o.IsoLevel = pgx.ReadCommitted
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	backoff := o.Backoff
+	for attempt := 1; ; attempt++ {
+		state := &txState{}
+		err := db.execTx(ctx, o, state, f)
+		if err == nil {
+			state.runSideEffects(db.Log)
+			return nil
+		}
+		if attempt > o.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		db.Log.Warn("Retrying transaction",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (db *DB) execTx(ctx context.Context, o TxOptions, state *txState, f func(q *Queries) error) (err error) {
+	tx, err := db.pool.BeginTx(ctx, o.pgxOptions())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit(ctx)
+			if err != nil {
+				_ = tx.Rollback(ctx)
+			}
+		} else {
+			txErr := tx.Rollback(ctx)
+			if txErr != nil {
+				err = fmt.Errorf("rollback failed %s; %w", txErr.Error(), err)
+			}
+		}
+	}()
+
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	q := &Queries{tx: tx, builder: &builder, state: state, timeouts: db.timeouts}
+
+	err = f(q)
+
+	return err
+}
+
+// SideEffect queues f, which does something outside of DB (e.g. sends an event), to run after the commit,
+// so rolled back writes never trigger it. Outside of a transaction f runs immediately.
+func (q *Queries) SideEffect(f func() error) error {
+	if q.state == nil {
+		return f()
+	}
+	q.state.sideEffects = append(q.state.sideEffects, f)
+	return nil
+}
+
+// runSideEffects runs every side effect, a failed one doesn't stop the rest
+func (s *txState) runSideEffects(log *zap.Logger) {
+	for _, f := range s.sideEffects {
+		if err := f(); err != nil {
+			log.Error("Failed to run side effect of committed transaction", zap.Error(err))
+		}
+	}
+}
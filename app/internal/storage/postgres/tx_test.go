@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pashagolub/pgxmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/M-Fisher/companies_api/app/config"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: `Serialization failure`,
+			err:  fmt.Errorf("query: %w", &pgconn.PgError{Code: "40001"}),
+			want: true,
+		},
+		{
+			name: `Deadlock`,
+			err:  &pgconn.PgError{Code: "40P01"},
+			want: true,
+		},
+		{
+			name: `Connection failure`,
+			err:  &pgconn.PgError{Code: "08006"},
+			want: true,
+		},
+		{
+			name: `Unique violation`,
+			err:  &pgconn.PgError{Code: "23505"},
+			want: false,
+		},
+		{
+			name: `Other error`,
+			err:  errors.New(`some error`),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func newTestDB(t *testing.T, conf *config.DB) (pgxmock.PgxConnIface, *DB) {
+	pgxMock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("Failed to start pgxmock: %v", err)
+	}
+	db, err := NewTestPostgres(pgxMock, conf, zap.NewExample())
+	if err != nil {
+		t.Fatalf("Failed to init test postgres")
+	}
+	return pgxMock, db
+}
+
+func TestDB_ExecTxRetry(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{TxMaxRetries: 2, TxRetryBackoff: time.Millisecond})
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("UPDATE companies").WillReturnError(&pgconn.PgError{Code: "40001"})
+	pgxMock.ExpectRollback()
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectExec("UPDATE companies").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	pgxMock.ExpectCommit()
+
+	calls := 0
+	err := db.ExecTx(context.Background(), func(q *Queries) error {
+		calls++
+		_, err := q.tx.Exec(context.Background(), "UPDATE companies SET name = 'test'")
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestDB_ExecTxRetriesExceeded(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{TxMaxRetries: 1, TxRetryBackoff: time.Millisecond})
+	for i := 0; i < 2; i++ {
+		pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+		pgxMock.ExpectExec("UPDATE companies").WillReturnError(&pgconn.PgError{Code: "40P01"})
+		pgxMock.ExpectRollback()
+	}
+
+	calls := 0
+	err := db.ExecTx(context.Background(), func(q *Queries) error {
+		calls++
+		_, err := q.tx.Exec(context.Background(), "UPDATE companies SET name = 'test'")
+		return err
+	})
+
+	assert.Equal(t, &pgconn.PgError{Code: "40P01"}, err)
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestDB_ExecTxSideEffectsAfterCommit(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{TxMaxRetries: 3, TxRetryBackoff: time.Millisecond})
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly})
+	pgxMock.ExpectCommit().WillReturnError(&pgconn.PgError{Code: "40001"})
+	pgxMock.ExpectRollback()
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly})
+	pgxMock.ExpectCommit()
+
+	sent := 0
+	err := db.ExecTx(context.Background(), func(q *Queries) error {
+		return q.SideEffect(func() error {
+			sent++
+			return nil
+		})
+	}, WithIsoLevel(pgx.Serializable), WithReadOnly())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestDB_ExecTxNoSideEffectsOnRollback(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectCommit().WillReturnError(errors.New(`commit failed`))
+	pgxMock.ExpectRollback()
+
+	sent := 0
+	err := db.ExecTx(context.Background(), func(q *Queries) error {
+		return q.SideEffect(func() error {
+			sent++
+			return nil
+		})
+	})
+
+	assert.EqualError(t, err, `commit failed`)
+	assert.Equal(t, 0, sent)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestDB_ExecTxSideEffectErr(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.ReadCommitted})
+	pgxMock.ExpectCommit()
+
+	sent := 0
+	err := db.ExecTx(context.Background(), func(q *Queries) error {
+		if err := q.SideEffect(func() error {
+			return errors.New(`send failed`)
+		}); err != nil {
+			return err
+		}
+		return q.SideEffect(func() error {
+			sent++
+			return nil
+		})
+	})
+
+	// the transaction is committed, failed side effect neither fails it nor stops the others
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
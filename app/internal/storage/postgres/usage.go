@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/pgxscan"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// incrementUsageSQL row lock of the counter serializes concurrent increments
+	incrementUsageSQL = `INSERT INTO usage_counters (tenant_id, subject, metric, window_start, count)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, subject, metric, window_start)
+		DO UPDATE SET count = usage_counters.count + EXCLUDED.count
+		RETURNING count`
+
+	getUsageSQL = `SELECT count FROM usage_counters
+		WHERE tenant_id = $1 AND subject = $2 AND metric = $3 AND window_start = $4`
+
+	// lockCompaniesQuotaSQL serializes checks of companies quota of the tenant until the transaction ends
+	lockCompaniesQuotaSQL = `SELECT pg_advisory_xact_lock(hashtext('companies_quota:' || $1))`
+
+	countCompaniesSQL = `SELECT count(*) FROM companies WHERE tenant_id = $1`
+
+	deleteExpiredUsageSQL = `DELETE FROM usage_counters WHERE window_start < $1`
+)
+
+type UsageQueries interface {
+	IncrementUsage(ctx context.Context, subject, metric string, window time.Time, n int64) (int64, error)
+	GetUsage(ctx context.Context, subject, metric string, window time.Time) (int64, error)
+	LockCompaniesQuota(ctx context.Context) error
+	CountCompanies(ctx context.Context) (int64, error)
+	DeleteExpiredUsage(ctx context.Context, before time.Time) error
+}
+
+// IncrementUsage adds n to the counter of the caller's tenant and returns its new value
+func (q *Queries) IncrementUsage(ctx context.Context, subject, metric string, window time.Time, n int64) (int64, error) {
+	ctx, cancel := q.queryContext(ctx, `IncrementUsage`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = q.tx.QueryRow(ctx, incrementUsageSQL, tenantID, subject, metric, window, n).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetUsage returns the counter of the caller's tenant, 0 if nothing was counted in the window
+func (q *Queries) GetUsage(ctx context.Context, subject, metric string, window time.Time) (int64, error) {
+	ctx, cancel := q.queryContext(ctx, `GetUsage`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = pgxscan.Get(ctx, q.tx, &count, getUsageSQL, tenantID, subject, metric, window)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	return count, nil
+}
+
+// LockCompaniesQuota locks companies quota of the caller's tenant until the transaction ends
+func (q *Queries) LockCompaniesQuota(ctx context.Context) error {
+	ctx, cancel := q.queryContext(ctx, `LockCompaniesQuota`, queryWrite)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.tx.Exec(ctx, lockCompaniesQuotaSQL, tenantID)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
+
+// CountCompanies returns count of companies stored by the caller's tenant
+func (q *Queries) CountCompanies(ctx context.Context) (int64, error) {
+	ctx, cancel := q.queryContext(ctx, `CountCompanies`, queryRead)
+	defer cancel()
+	tenantID, err := tenantFromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = q.tx.QueryRow(ctx, countCompaniesSQL, tenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("query: %w", err)
+	}
+
+	return count, nil
+}
+
+// DeleteExpiredUsage deletes counters of all tenants whose windows started before the time
+func (q *Queries) DeleteExpiredUsage(ctx context.Context, before time.Time) error {
+	ctx, cancel := q.queryContext(ctx, `DeleteExpiredUsage`, queryWrite)
+	defer cancel()
+
+	_, err := q.tx.Exec(ctx, deleteExpiredUsageSQL, before)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	return nil
+}
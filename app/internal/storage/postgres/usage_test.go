@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/M-Fisher/companies_api/app/config"
+	"github.com/M-Fisher/companies_api/app/internal/identity"
+)
+
+func TestQueries_IncrementUsage(t *testing.T) {
+	window := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("INSERT INTO usage_counters .+ ON CONFLICT .+ RETURNING count").
+		WithArgs(`acme`, `tenant`, `requests`, window, int64(1)).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}).AddRow(int64(3)))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	got, err := db.Queries.(*Queries).IncrementUsage(ctx, `tenant`, `requests`, window, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_GetUsageNotCounted(t *testing.T) {
+	window := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pgxMock, db := newTestDB(t, &config.DB{})
+	pgxMock.ExpectQuery("SELECT count FROM usage_counters").
+		WithArgs(`acme`, `api_key:7`, `requests`, window).
+		WillReturnRows(pgxMock.NewRows([]string{`count`}))
+
+	ctx := identity.ToContext(context.Background(), identity.Identity{UserID: 1, TenantID: `acme`})
+	got, err := db.Queries.(*Queries).GetUsage(ctx, `api_key:7`, `requests`, window)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), got)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
+
+func TestQueries_CountCompaniesWithoutTenant(t *testing.T) {
+	pgxMock, db := newTestDB(t, &config.DB{})
+
+	_, err := db.Queries.(*Queries).CountCompanies(context.Background())
+
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	assert.NoError(t, pgxMock.ExpectationsWereMet())
+}
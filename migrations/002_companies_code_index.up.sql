@@ -0,0 +1,3 @@
+-- plain index on code can't serve LIKE search, code lookups use companies_tenant_code_idx (003)
+-- and search uses companies_code_trgm_idx (010)
+DROP INDEX IF EXISTS companies_code_idx;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS companies_tenant_code_idx;
+ALTER TABLE companies DROP COLUMN IF EXISTS tenant_id;
\ No newline at end of file
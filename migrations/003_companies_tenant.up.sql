@@ -0,0 +1,3 @@
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+DROP INDEX IF EXISTS companies_code_idx;
+CREATE INDEX IF NOT EXISTS companies_tenant_code_idx ON companies(tenant_id, code);
\ No newline at end of file
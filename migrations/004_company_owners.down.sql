@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS company_collaborators;
+ALTER TABLE companies DROP COLUMN IF EXISTS owner_id;
\ No newline at end of file
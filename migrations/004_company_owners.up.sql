@@ -0,0 +1,8 @@
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS owner_id BIGINT DEFAULT NULL;
+CREATE TABLE IF NOT EXISTS company_collaborators(
+    company_id  INTEGER     NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    user_id     BIGINT      NOT NULL,
+    role        TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (company_id, user_id)
+);
\ No newline at end of file
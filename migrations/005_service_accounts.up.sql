@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS service_accounts(
+    id          serial      PRIMARY KEY,
+    tenant_id   TEXT        NOT NULL DEFAULT 'default',
+    name        TEXT        NOT NULL,
+    client_id   TEXT        NOT NULL UNIQUE,
+    secret_hash TEXT        NOT NULL,
+    scopes      TEXT[]      NOT NULL DEFAULT '{}',
+    disabled    BOOLEAN     NOT NULL DEFAULT false,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys(
+    id           serial      PRIMARY KEY,
+    tenant_id    TEXT        NOT NULL DEFAULT 'default',
+    user_id      BIGINT      NOT NULL,
+    name         TEXT        NOT NULL,
+    prefix       TEXT        NOT NULL UNIQUE,
+    key_hash     TEXT        NOT NULL,
+    scopes       TEXT[]      NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ DEFAULT NULL,
+    revoked_at   TIMESTAMPTZ DEFAULT NULL
+);
+CREATE INDEX IF NOT EXISTS api_keys_tenant_user_idx ON api_keys(tenant_id, user_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens(
+    jti         TEXT        PRIMARY KEY,
+    tenant_id   TEXT        NOT NULL DEFAULT 'default',
+    user_id     BIGINT      NOT NULL,
+    expires_at  TIMESTAMPTZ DEFAULT NULL,
+    revoked_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS user_token_revocations(
+    tenant_id      TEXT        NOT NULL,
+    user_id        BIGINT      NOT NULL,
+    revoked_before TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, user_id)
+);
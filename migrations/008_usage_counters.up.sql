@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS usage_counters(
+    tenant_id    TEXT        NOT NULL,
+    subject      TEXT        NOT NULL,
+    metric       TEXT        NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count        BIGINT      NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, subject, metric, window_start)
+);
+CREATE INDEX IF NOT EXISTS usage_counters_window_start_idx ON usage_counters(window_start);
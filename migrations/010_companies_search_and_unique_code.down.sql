@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS companies_code_trgm_idx;
+DROP INDEX IF EXISTS companies_tenant_code_key;
\ No newline at end of file
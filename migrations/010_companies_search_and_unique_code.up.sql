@@ -0,0 +1,3 @@
+CREATE UNIQUE INDEX IF NOT EXISTS companies_tenant_code_key ON companies(tenant_id, code) WHERE code <> '';
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS companies_code_trgm_idx ON companies USING gin (code gin_trgm_ops);
\ No newline at end of file